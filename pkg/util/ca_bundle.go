@@ -0,0 +1,338 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// InjectTrustedCABundleLabel - label requesting the cluster network operator
+	// to inject the trusted CA bundle into a ConfigMap
+	InjectTrustedCABundleLabel = "config.openshift.io/inject-trusted-cabundle"
+	// InjectedCABundleKey - ConfigMap key holding the injected CA bundle
+	InjectedCABundleKey = "ca-bundle.crt"
+	// CombinedCABundleKey - secret key holding the combined CA bundle
+	CombinedCABundleKey = "tls-ca-bundle.pem"
+	// CombinedCABundleLabel - label set on combined CA bundle secrets
+	CombinedCABundleLabel = "combined-ca-bundle"
+	// CACertKey - secret key holding a single CA certificate
+	CACertKey = "ca.crt"
+)
+
+// GetInjectedCABundle - return the CA bundle injected into a ConfigMap labeled
+// with config.openshift.io/inject-trusted-cabundle=true in the namespace
+func GetInjectedCABundle(ctx context.Context, c client.Client, namespace string) ([]byte, error) {
+	configMaps := &corev1.ConfigMapList{}
+	err := c.List(ctx, configMaps,
+		client.InNamespace(namespace),
+		client.MatchingLabels{InjectTrustedCABundleLabel: "true"},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cm := range configMaps.Items {
+		if bundle, ok := cm.Data[InjectedCABundleKey]; ok && bundle != "" {
+			return []byte(bundle), nil
+		}
+	}
+
+	return nil, fmt.Errorf("No injected CA bundle found in namespace %s", namespace)
+}
+
+// BundleBlockError - parse error of a single PEM block in a CA bundle
+type BundleBlockError struct {
+	// Index of the offending PEM block in the bundle
+	Index int
+	Err   error
+}
+
+// BundleInfo - summary of the certificates in a CA bundle
+type BundleInfo struct {
+	Count            int
+	Subjects         []string
+	EarliestNotAfter time.Time
+	Errors           []BundleBlockError
+}
+
+// ExpiresWithin - returns true if a certificate of the bundle expires within the window
+func (b BundleInfo) ExpiresWithin(window time.Duration) bool {
+	return b.Count > 0 && time.Now().Add(window).After(b.EarliestNotAfter)
+}
+
+// InspectCABundle - parse all PEM blocks of a CA bundle and report the number
+// of certificates, their subjects and the earliest expiry. Blocks which can not
+// be parsed, including truncated ones and trailing garbage, are reported with
+// their index and result in an error.
+func InspectCABundle(data []byte) (BundleInfo, error) {
+	info := BundleInfo{}
+
+	rest := data
+	for index := 0; ; index++ {
+		var block *pem.Block
+		var err error
+		block, rest, err = nextPEMBlock(rest)
+		if err != nil {
+			info.Errors = append(info.Errors, BundleBlockError{Index: index, Err: err})
+			index++
+		}
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			info.Errors = append(info.Errors, BundleBlockError{
+				Index: index,
+				Err:   fmt.Errorf("unexpected PEM block type %s", block.Type),
+			})
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			info.Errors = append(info.Errors, BundleBlockError{Index: index, Err: err})
+			continue
+		}
+
+		info.Count++
+		info.Subjects = append(info.Subjects, cert.Subject.String())
+		if info.EarliestNotAfter.IsZero() || cert.NotAfter.Before(info.EarliestNotAfter) {
+			info.EarliestNotAfter = cert.NotAfter
+		}
+	}
+
+	if len(info.Errors) > 0 {
+		return info, fmt.Errorf("CA bundle block %d is invalid: %v", info.Errors[0].Index, info.Errors[0].Err)
+	}
+	if info.Count == 0 {
+		return info, fmt.Errorf("No certificates found in CA bundle")
+	}
+
+	return info, nil
+}
+
+// InspectCABundleSecret - read the CA bundle from the key of the secret, the
+// combined CA bundle key if empty, and inspect it using InspectCABundle.
+// Returns true if a certificate of the bundle expires within the window, so
+// the caller can report it, e.g. in an informational condition.
+func InspectCABundleSecret(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	name string,
+	key string,
+	window time.Duration,
+) (BundleInfo, bool, error) {
+	if key == "" {
+		key = CombinedCABundleKey
+	}
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret)
+	if err != nil {
+		return BundleInfo{}, false, err
+	}
+
+	data, ok := secret.Data[key]
+	if !ok {
+		return BundleInfo{}, false, fmt.Errorf("Secret %s has no key %s", name, key)
+	}
+
+	info, err := InspectCABundle(data)
+	if err != nil {
+		return info, false, fmt.Errorf("Secret %s: %v", name, err)
+	}
+
+	return info, info.ExpiresWithin(window), nil
+}
+
+// pemBeginMarker - start of the header line of a PEM block
+var pemBeginMarker = []byte("-----BEGIN")
+
+// nextPEMBlock - decode the next PEM block of data. pem.Decode silently skips
+// blocks it can not decode, e.g. a truncated one or one with corrupt base64,
+// so an error gets returned if a block got skipped before the returned one,
+// or, at the end of data, if anything but whitespace remains.
+func nextPEMBlock(data []byte) (*pem.Block, []byte, error) {
+	block, rest := pem.Decode(data)
+	if block == nil {
+		if len(bytes.TrimSpace(data)) > 0 {
+			return nil, rest, fmt.Errorf("invalid or truncated PEM data")
+		}
+		return nil, rest, nil
+	}
+
+	consumed := data[:len(data)-len(rest)]
+	if bytes.Count(consumed, pemBeginMarker) > 1 {
+		return block, rest, fmt.Errorf("invalid PEM block")
+	}
+	return block, rest, nil
+}
+
+// MergeCABundles - merge the PEM encoded CA bundles into a single bundle.
+// Identical certificates are de-duplicated by their fingerprint, expired ones
+// dropped, and the result is ordered by fingerprint so the bundle, and with
+// it the hash of a secret it gets stored in, stays the same across reconciles.
+// Empty inputs are skipped, an input with an invalid, truncated or trailing
+// garbage PEM block returns an error naming the input.
+func MergeCABundles(bundles ...[]byte) ([]byte, error) {
+	now := time.Now()
+	certs := map[string]*x509.Certificate{}
+
+	for i, bundle := range bundles {
+		if len(bytes.TrimSpace(bundle)) == 0 {
+			continue
+		}
+
+		if !bytes.Contains(bundle, pemBeginMarker) {
+			return nil, fmt.Errorf("CA bundle %d contains no PEM encoded certificate", i)
+		}
+
+		rest := bundle
+		found := false
+		for {
+			var block *pem.Block
+			var err error
+			block, rest, err = nextPEMBlock(rest)
+			if err != nil {
+				return nil, fmt.Errorf("CA bundle %d is invalid: %v", i, err)
+			}
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				return nil, fmt.Errorf("CA bundle %d contains unexpected PEM block type %s", i, block.Type)
+			}
+
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("CA bundle %d is invalid: %v", i, err)
+			}
+			found = true
+
+			if now.After(cert.NotAfter) {
+				continue
+			}
+			sum := sha256.Sum256(cert.Raw)
+			certs[hex.EncodeToString(sum[:])] = cert
+		}
+		if !found {
+			return nil, fmt.Errorf("CA bundle %d contains no PEM encoded certificate", i)
+		}
+	}
+
+	fingerprints := make([]string, 0, len(certs))
+	for fp := range certs {
+		fingerprints = append(fingerprints, fp)
+	}
+	sort.Strings(fingerprints)
+
+	merged := []byte{}
+	for _, fp := range fingerprints {
+		merged = append(merged, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certs[fp].Raw})...)
+	}
+
+	return merged, nil
+}
+
+// CreateCombinedCABundleSecret - merge the CA certificates of the ca.crt and
+// tls-ca-bundle.pem keys of the source secrets using MergeCABundles and write
+// them to the tls-ca-bundle.pem key of the target secret, labeled with
+// combined-ca-bundle. The target only gets updated when its content changed.
+// Fails if a source is missing or has no valid, i.e. unexpired, certificate.
+// Returns the hash of the target secret data.
+func CreateCombinedCABundleSecret(
+	ctx context.Context,
+	c client.Client,
+	sources []types.NamespacedName,
+	target types.NamespacedName,
+) (string, error) {
+	bundles := [][]byte{}
+	for _, source := range sources {
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, source, secret)
+		if err != nil {
+			if k8s_errors.IsNotFound(err) {
+				return "", fmt.Errorf("CA secret %s not found", source)
+			}
+			return "", err
+		}
+
+		sourceBundles := [][]byte{secret.Data[CACertKey], secret.Data[CombinedCABundleKey]}
+		valid, err := MergeCABundles(sourceBundles...)
+		if err != nil {
+			return "", fmt.Errorf("CA secret %s has no usable PEM: %v", source, err)
+		}
+		if len(valid) == 0 {
+			return "", fmt.Errorf("CA secret %s has no valid certificate in %s or %s", source, CACertKey, CombinedCABundleKey)
+		}
+		bundles = append(bundles, sourceBundles...)
+	}
+
+	bundle, err := MergeCABundles(bundles...)
+	if err != nil {
+		return "", err
+	}
+	data := map[string][]byte{CombinedCABundleKey: bundle}
+	hash, err := ObjectHash(data)
+	if err != nil {
+		return "", err
+	}
+
+	secret := &corev1.Secret{}
+	err = c.Get(ctx, target, secret)
+	if err != nil && k8s_errors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      target.Name,
+				Namespace: target.Namespace,
+				Labels:    map[string]string{CombinedCABundleLabel: "true"},
+			},
+			Data: data,
+		}
+		return hash, c.Create(ctx, secret)
+	} else if err != nil {
+		return "", err
+	}
+
+	if secret.Labels[CombinedCABundleLabel] == "true" && bytes.Equal(secret.Data[CombinedCABundleKey], bundle) && len(secret.Data) == 1 {
+		return hash, nil
+	}
+
+	if secret.Labels == nil {
+		secret.Labels = map[string]string{}
+	}
+	secret.Labels[CombinedCABundleLabel] = "true"
+	secret.Data = data
+
+	return hash, c.Update(ctx, secret)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+/*
+Merge CA bundles from several volumes into a single file at pod startup:
+
+podSpec.Volumes = append(podSpec.Volumes, util.CABundleVolume())
+podSpec.InitContainers = append(podSpec.InitContainers,
+	util.CreateCABundleInitContainer(image, []string{"cluster-ca", "custom-ca"}, "/etc/pki/ca-bundle/tls-ca-bundle.pem"))
+
+The sources are names of secret or configmap volumes of the pod, all their
+files get concatenated. The service containers mount CABundleVolumeName at
+the directory of dest.
+*/
+
+const (
+	// CABundleVolumeName - name of the emptyDir volume holding the merged CA bundle
+	CABundleVolumeName = "ca-bundle"
+	// CABundleInitContainerName - name of the init container merging the CA bundles
+	CABundleInitContainerName = "ca-bundle-init"
+	// caBundleSourcePath - path the source volumes get mounted below
+	caBundleSourcePath = "/var/lib/ca-bundle-sources"
+)
+
+// CABundleVolume - emptyDir volume the merged CA bundle gets written to
+func CABundleVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: CABundleVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+}
+
+// CreateCABundleInitContainer - init container which concatenates all files
+// of the source volumes into the dest file on the CA bundle volume. A newline
+// gets added after each file, as CA files often lack the trailing one, which
+// would join the END and BEGIN lines of two certificates.
+func CreateCABundleInitContainer(image string, sources []string, dest string) corev1.Container {
+	files := []string{}
+	mounts := []corev1.VolumeMount{
+		{
+			Name:      CABundleVolumeName,
+			MountPath: path.Dir(dest),
+		},
+	}
+	for _, source := range sources {
+		mountPath := path.Join(caBundleSourcePath, source)
+		files = append(files, shellQuote(mountPath)+"/*")
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      source,
+			MountPath: mountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	return corev1.Container{
+		Name:         CABundleInitContainerName,
+		Image:        image,
+		Command:      []string{"/bin/sh", "-c"},
+		Args:         []string{caBundleInitScript(files, dest)},
+		VolumeMounts: mounts,
+	}
+}
+
+// caBundleInitScript - shell script concatenating the files, which are shell
+// globs, into dest. Unmatched globs of empty source volumes are skipped.
+func caBundleInitScript(files []string, dest string) string {
+	return fmt.Sprintf(`for f in %s; do [ -f "$f" ] || continue; cat "$f"; echo; done > %s`,
+		strings.Join(files, " "), shellQuote(dest))
+}
+
+// shellQuote - quote s as a single word for the shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
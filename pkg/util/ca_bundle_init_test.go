@@ -0,0 +1,85 @@
+package util
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestCreateCABundleInitContainer(t *testing.T) {
+	container := CreateCABundleInitContainer("image", []string{"cluster-ca", "custom-ca"}, "/etc/pki/ca-bundle/tls-ca-bundle.pem")
+
+	expectedArgs := []string{
+		`for f in '/var/lib/ca-bundle-sources/cluster-ca'/* '/var/lib/ca-bundle-sources/custom-ca'/*; do [ -f "$f" ] || continue; cat "$f"; echo; done > '/etc/pki/ca-bundle/tls-ca-bundle.pem'`,
+	}
+	if !reflect.DeepEqual(container.Args, expectedArgs) {
+		t.Errorf("Expected: %v; Got: %v", expectedArgs, container.Args)
+	}
+
+	expectedMounts := []corev1.VolumeMount{
+		{Name: CABundleVolumeName, MountPath: "/etc/pki/ca-bundle"},
+		{Name: "cluster-ca", MountPath: "/var/lib/ca-bundle-sources/cluster-ca", ReadOnly: true},
+		{Name: "custom-ca", MountPath: "/var/lib/ca-bundle-sources/custom-ca", ReadOnly: true},
+	}
+	if !reflect.DeepEqual(container.VolumeMounts, expectedMounts) {
+		t.Errorf("Expected: %v; Got: %v", expectedMounts, container.VolumeMounts)
+	}
+}
+
+func TestCABundleInitScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ca-bundle-init")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	write := func(source string, name string, data []byte) {
+		sourceDir := filepath.Join(dir, "sources", source)
+		if err := os.MkdirAll(sourceDir, 0755); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(sourceDir, name), data, 0644); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	// CA files without trailing newline
+	write("cluster-ca", "ca.crt", bytes.TrimSpace(testCertPEM(t, "cluster", now.Add(time.Hour))))
+	write("custom-ca", "a.pem", bytes.TrimSpace(testCertPEM(t, "custom-a", now.Add(time.Hour))))
+	write("custom-ca", "b.pem", testCertPEM(t, "custom-b", now.Add(time.Hour)))
+	if err := os.MkdirAll(filepath.Join(dir, "sources", "empty-ca"), 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dest := filepath.Join(dir, "bundle dir", "tls-ca-bundle.pem")
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	container := CreateCABundleInitContainer("image", []string{"cluster-ca", "empty-ca", "custom-ca"}, dest)
+	script := strings.ReplaceAll(container.Args[0], caBundleSourcePath, filepath.Join(dir, "sources"))
+
+	out, err := exec.Command(container.Command[0], append(container.Command[1:], script)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Unexpected error running %q: %v: %s", script, err, out)
+	}
+
+	bundle, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := InspectCABundle(bundle)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Count != 3 {
+		t.Errorf("Expected 3 certificates; Got: %v", info.Subjects)
+	}
+}
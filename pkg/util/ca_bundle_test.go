@@ -0,0 +1,292 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetInjectedCABundle(t *testing.T) {
+	c := fake.NewFakeClient(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "unlabeled", Namespace: "test"},
+			Data:       map[string]string{InjectedCABundleKey: "wrong"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "trusted-ca",
+				Namespace: "test",
+				Labels:    map[string]string{InjectTrustedCABundleLabel: "true"},
+			},
+			Data: map[string]string{InjectedCABundleKey: "bundle"},
+		},
+	)
+
+	bundle, err := GetInjectedCABundle(context.TODO(), c, "test")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(bundle) != "bundle" {
+		t.Errorf("Expected: bundle; Got: %s", bundle)
+	}
+
+	if _, err := GetInjectedCABundle(context.TODO(), c, "other"); err == nil {
+		t.Errorf("Didn't get expected error for namespace without injected CA bundle")
+	}
+}
+
+func testCertPEM(t *testing.T, cn string, notAfter time.Time) []byte {
+	certPEM, _, err := GenerateSelfSignedCert([]string{cn}, notAfter)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %v", err)
+	}
+	return certPEM
+}
+
+func TestInspectCABundle(t *testing.T) {
+	now := time.Now()
+	good := testCertPEM(t, "good", now.Add(365*24*time.Hour))
+	expired := testCertPEM(t, "expired", now.Add(-time.Hour))
+	garbage := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")})
+
+	info, err := InspectCABundle(append(append([]byte{}, good...), expired...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Count != 2 || info.Subjects[0] != "CN=good" || info.Subjects[1] != "CN=expired" {
+		t.Errorf("Unexpected bundle info: %v", info)
+	}
+	if !info.ExpiresWithin(0) {
+		t.Errorf("Expected bundle with expired certificate to expire within 0")
+	}
+
+	info, err = InspectCABundle(good)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.ExpiresWithin(24*time.Hour) || !info.ExpiresWithin(2*365*24*time.Hour) {
+		t.Errorf("Unexpected expiry window result for %v", info.EarliestNotAfter)
+	}
+
+	info, err = InspectCABundle(append(append([]byte{}, good...), garbage...))
+	if err == nil {
+		t.Errorf("Didn't get expected error for bundle with garbage block")
+	}
+	if info.Count != 1 || len(info.Errors) != 1 || info.Errors[0].Index != 1 {
+		t.Errorf("Unexpected bundle info: %v", info)
+	}
+
+	if _, err := InspectCABundle([]byte("no pem")); err == nil {
+		t.Errorf("Didn't get expected error for bundle without certificates")
+	}
+}
+
+func TestInspectCABundleCorruptBlocks(t *testing.T) {
+	now := time.Now()
+	good := testCertPEM(t, "good", now.Add(365*24*time.Hour))
+	other := testCertPEM(t, "other", now.Add(365*24*time.Hour))
+	truncated := other[:len(other)/2]
+	corrupt := []byte("-----BEGIN CERTIFICATE-----\nnot!base64!at!all\n-----END CERTIFICATE-----\n")
+
+	tests := []struct {
+		name   string
+		bundle []byte
+		count  int
+		index  int
+	}{
+		{"truncated trailing block", concatPEM(good, truncated), 1, 1},
+		{"corrupt base64 trailing block", concatPEM(good, corrupt), 1, 1},
+		{"corrupt base64 block in the middle", concatPEM(good, corrupt, other), 2, 1},
+		{"trailing garbage", concatPEM(good, []byte("garbage")), 1, 1},
+	}
+
+	for _, test := range tests {
+		info, err := InspectCABundle(test.bundle)
+		if err == nil {
+			t.Errorf("%s: Didn't get expected error", test.name)
+			continue
+		}
+		if info.Count != test.count || len(info.Errors) != 1 || info.Errors[0].Index != test.index {
+			t.Errorf("%s: Expected: %d certs, error at block %d; Got: %d certs, %v", test.name, test.count, test.index, info.Count, info.Errors)
+		}
+	}
+
+	c := fake.NewFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "truncated", Namespace: "test"},
+		Data:       map[string][]byte{CombinedCABundleKey: concatPEM(good, truncated)},
+	})
+	if _, _, err := InspectCABundleSecret(context.TODO(), c, "test", "truncated", "", time.Hour); err == nil {
+		t.Errorf("Didn't get expected error for secret with truncated bundle")
+	}
+}
+
+func concatPEM(blocks ...[]byte) []byte {
+	return bytes.Join(blocks, nil)
+}
+
+func TestInspectCABundleSecret(t *testing.T) {
+	now := time.Now()
+	good := testCertPEM(t, "good", now.Add(365*24*time.Hour))
+	soon := testCertPEM(t, "soon", now.Add(24*time.Hour))
+	garbage := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("garbage")})
+
+	c := fake.NewFakeClient(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "good", Namespace: "test"},
+			Data:       map[string][]byte{CombinedCABundleKey: good},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "soon", Namespace: "test"},
+			Data:       map[string][]byte{CACertKey: append(append([]byte{}, good...), soon...)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "garbage", Namespace: "test"},
+			Data:       map[string][]byte{CombinedCABundleKey: garbage},
+		},
+	)
+
+	tests := []struct {
+		name     string
+		key      string
+		count    int
+		expiring bool
+		err      bool
+	}{
+		{"good", "", 1, false, false},
+		{"soon", CACertKey, 2, true, false},
+		{"soon", "", 0, false, true},
+		{"garbage", "", 0, false, true},
+		{"missing", "", 0, false, true},
+	}
+
+	for _, test := range tests {
+		info, expiring, err := InspectCABundleSecret(context.TODO(), c, "test", test.name, test.key, 7*24*time.Hour)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("Didn't get expected error for secret %s key %q", test.name, test.key)
+		case !test.err && err != nil:
+			t.Errorf("Unexpected error for secret %s key %q: %v", test.name, test.key, err)
+		case !test.err && (info.Count != test.count || expiring != test.expiring):
+			t.Errorf("Secret %s; Expected: %d %v; Got: %d %v", test.name, test.count, test.expiring, info.Count, expiring)
+		}
+	}
+}
+
+func TestMergeCABundles(t *testing.T) {
+	now := time.Now()
+	one := testCertPEM(t, "one", now.Add(365*24*time.Hour))
+	two := testCertPEM(t, "two", now.Add(365*24*time.Hour))
+	expired := testCertPEM(t, "expired", now.Add(-time.Hour))
+
+	merged, err := MergeCABundles(append(append([]byte{}, one...), expired...), two, one, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := InspectCABundle(merged)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.Count != 2 {
+		t.Errorf("Expected 2 certificates; Got: %v", info.Subjects)
+	}
+
+	reordered, err := MergeCABundles(two, one)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(reordered) != string(merged) {
+		t.Errorf("Expected merged bundle to not depend on the input order")
+	}
+
+	_, err = MergeCABundles(one, []byte("garbage"))
+	if err == nil || err.Error() != "CA bundle 1 contains no PEM encoded certificate" {
+		t.Errorf("Expected error naming bundle 1; Got: %v", err)
+	}
+
+	truncated := two[:len(two)/2]
+	corrupt := []byte("-----BEGIN CERTIFICATE-----\nnot!base64!at!all\n-----END CERTIFICATE-----\n")
+	for _, invalid := range [][]byte{concatPEM(two, truncated), concatPEM(two, corrupt), concatPEM(corrupt, two)} {
+		_, err = MergeCABundles(one, invalid)
+		if err == nil || !strings.HasPrefix(err.Error(), "CA bundle 1 is invalid") {
+			t.Errorf("Expected error naming bundle 1; Got: %v", err)
+		}
+	}
+}
+
+func TestCreateCombinedCABundleSecret(t *testing.T) {
+	now := time.Now()
+	internal := testCertPEM(t, "internal", now.Add(365*24*time.Hour))
+	ldap := testCertPEM(t, "ldap", now.Add(365*24*time.Hour))
+	custom := testCertPEM(t, "custom", now.Add(365*24*time.Hour))
+	expired := testCertPEM(t, "expired", now.Add(-time.Hour))
+	c := fake.NewFakeClient(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "internal", Namespace: "test"},
+			Data:       map[string][]byte{CACertKey: internal},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ldap", Namespace: "test"},
+			Data: map[string][]byte{
+				// no trailing newline before the bundle
+				CACertKey:           bytes.TrimSpace(custom),
+				CombinedCABundleKey: append(append([]byte{}, ldap...), internal...),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "expired", Namespace: "test"},
+			Data:       map[string][]byte{CACertKey: expired},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "garbage", Namespace: "test"},
+			Data:       map[string][]byte{CACertKey: []byte("garbage")},
+		},
+	)
+	target := types.NamespacedName{Name: "combined-ca-bundle", Namespace: "test"}
+	sources := []types.NamespacedName{
+		{Name: "internal", Namespace: "test"},
+		{Name: "ldap", Namespace: "test"},
+	}
+
+	hash, err := CreateCombinedCABundleSecret(context.TODO(), c, sources, target)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(context.TODO(), target, secret); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := InspectCABundle(secret.Data[CombinedCABundleKey])
+	if err != nil || info.Count != 3 {
+		t.Errorf("Expected 3 de-duplicated certificates; Got: %v, %v", info.Subjects, err)
+	}
+	if secret.Labels[CombinedCABundleLabel] != "true" {
+		t.Errorf("Expected %s label; Got: %v", CombinedCABundleLabel, secret.Labels)
+	}
+
+	again, err := CreateCombinedCABundleSecret(context.TODO(), c, sources, target)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	unchanged := &corev1.Secret{}
+	if err := c.Get(context.TODO(), target, unchanged); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if again != hash || unchanged.ResourceVersion != secret.ResourceVersion {
+		t.Errorf("Expected no update when the content is unchanged")
+	}
+
+	for _, source := range []string{"missing", "garbage", "expired"} {
+		_, err = CreateCombinedCABundleSecret(context.TODO(), c, []types.NamespacedName{{Name: source, Namespace: "test"}}, target)
+		if err == nil || !strings.Contains(err.Error(), "test/"+source) {
+			t.Errorf("Expected error naming secret %s; Got: %v", source, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+const (
+	// certKeyBitSize - size of the RSA keys of generated certificates
+	certKeyBitSize = 2048
+)
+
+// GenerateSelfSignedCert creates a self-signed certificate for the dnsNames,
+// valid until notAfter, which can also be used as CA to sign other
+// certificates. Returns the certificate and RSA private key in PEM format.
+func GenerateSelfSignedCert(dnsNames []string, notAfter time.Time) ([]byte, []byte, error) {
+	key, err := GeneratePrivateKey(certKeyBitSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := certTemplate(dnsNames, notAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage |= x509.KeyUsageCertSign
+
+	return createCert(template, template, &key.PublicKey, key, key)
+}
+
+// GenerateCASignedCert creates a certificate for the dnsNames, valid until
+// notAfter, signed by the CA. Returns the certificate and RSA private key in
+// PEM format.
+func GenerateCASignedCert(caCertPEM []byte, caKeyPEM []byte, dnsNames []string, notAfter time.Time) ([]byte, []byte, error) {
+	caCert, err := ParseCertificatePEM(caCertPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, _ := pem.Decode(caKeyPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("No PEM data found in CA key")
+	}
+	caKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := GeneratePrivateKey(certKeyBitSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := certTemplate(dnsNames, notAfter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return createCert(template, caCert, &key.PublicKey, caKey, key)
+}
+
+// ParseCertificatePEM parses the first certificate of the PEM data, which
+// is the leaf certificate of a chain
+func ParseCertificatePEM(certPEM []byte) (*x509.Certificate, error) {
+	rest := certPEM
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("No certificate found in PEM data")
+		}
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+}
+
+// CertFingerprint returns the hex encoded SHA-256 fingerprint of the DER of
+// the leaf certificate
+func CertFingerprint(certPEM []byte) (string, error) {
+	cert, err := ParseCertificatePEM(certPEM)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ShouldRenew returns true when the leaf certificate expires within
+// renewBefore, aligned with the cert-manager renewBefore semantics
+func ShouldRenew(certPEM []byte, renewBefore time.Duration) (bool, error) {
+	cert, err := ParseCertificatePEM(certPEM)
+	if err != nil {
+		return false, err
+	}
+	return !time.Now().Add(renewBefore).Before(cert.NotAfter), nil
+}
+
+func certTemplate(dnsNames []string, notAfter time.Time) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	cn := "localhost"
+	if len(dnsNames) > 0 {
+		cn = dnsNames[0]
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}, nil
+}
+
+func createCert(
+	template *x509.Certificate,
+	parent *x509.Certificate,
+	pub *rsa.PublicKey,
+	signer *rsa.PrivateKey,
+	key *rsa.PrivateKey,
+) ([]byte, []byte, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return certPEM, []byte(EncodePrivateKeyToPEM(key)), nil
+}
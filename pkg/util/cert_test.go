@@ -0,0 +1,109 @@
+package util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	certPEM, keyPEM, err := GenerateSelfSignedCert([]string{"nova.test.svc"}, notAfter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		t.Errorf("Certificate and key don't match: %v", err)
+	}
+
+	cert, err := ParseCertificatePEM(certPEM)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !cert.NotAfter.Equal(notAfter) || !cert.IsCA {
+		t.Errorf("Unexpected certificate: NotAfter %v, IsCA %v", cert.NotAfter, cert.IsCA)
+	}
+	if err := cert.VerifyHostname("nova.test.svc"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestGenerateCASignedCert(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour)
+	caCertPEM, caKeyPEM, err := GenerateSelfSignedCert([]string{"ca"}, notAfter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	certPEM, keyPEM, err := GenerateCASignedCert(caCertPEM, caKeyPEM, []string{"nova.test.svc"}, notAfter)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		t.Errorf("Certificate and key don't match: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCertPEM)
+	cert, _ := ParseCertificatePEM(certPEM)
+	if _, err := cert.Verify(x509.VerifyOptions{DNSName: "nova.test.svc", Roots: roots}); err != nil {
+		t.Errorf("Certificate not signed by CA: %v", err)
+	}
+
+	if _, _, err := GenerateCASignedCert([]byte("garbage"), caKeyPEM, nil, notAfter); err == nil {
+		t.Errorf("Didn't get expected error for invalid CA certificate")
+	}
+}
+
+func TestCertFingerprint(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour)
+	cert1, _, _ := GenerateSelfSignedCert([]string{"one"}, notAfter)
+	cert2, _, _ := GenerateSelfSignedCert([]string{"two"}, notAfter)
+
+	fp1, err := CertFingerprint(cert1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(fp1) != 64 {
+		t.Errorf("Expected 64 hex characters; Got: %s", fp1)
+	}
+	if again, _ := CertFingerprint(cert1); again != fp1 {
+		t.Errorf("Expected stable fingerprint; Got: %s and %s", fp1, again)
+	}
+
+	// the leaf of a chain is used
+	chain := append(append([]byte{}, cert1...), cert2...)
+	if leaf, _ := CertFingerprint(chain); leaf != fp1 {
+		t.Errorf("Expected fingerprint of the leaf; Got: %s", leaf)
+	}
+
+	if fp2, _ := CertFingerprint(cert2); fp2 == fp1 {
+		t.Errorf("Expected different fingerprints for different certificates")
+	}
+
+	if _, err := CertFingerprint([]byte("garbage")); err == nil {
+		t.Errorf("Didn't get expected error for invalid PEM")
+	}
+}
+
+func TestShouldRenew(t *testing.T) {
+	certPEM, _, err := GenerateSelfSignedCert([]string{"nova"}, time.Now().Add(10*24*time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for renewBefore, expected := range map[time.Duration]bool{
+		24 * time.Hour:      false,
+		30 * 24 * time.Hour: true,
+	} {
+		renew, err := ShouldRenew(certPEM, renewBefore)
+		if err != nil || renew != expected {
+			t.Errorf("renewBefore %v; Expected: %v; Got: %v, %v", renewBefore, expected, renew, err)
+		}
+	}
+
+	if _, err := ShouldRenew([]byte("garbage"), time.Hour); err == nil {
+		t.Errorf("Didn't get expected error for invalid PEM")
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMapRef - ConfigMap to get with GetConfigMapsWithOptions
+type ConfigMapRef struct {
+	Name string
+	// Optional ConfigMaps are skipped when missing
+	Optional bool
+}
+
+// MissingConfigMapsError - returned when required ConfigMaps are missing
+type MissingConfigMapsError struct {
+	Namespace string
+	Names     []string
+}
+
+func (e *MissingConfigMapsError) Error() string {
+	return fmt.Sprintf("ConfigMaps missing in namespace %s: %s", e.Namespace, strings.Join(e.Names, ", "))
+}
+
+// GetConfigMap - get the ConfigMap from the explicit namespace, which can
+// differ from the namespace of the CR, and return it with the hash of its data
+func GetConfigMap(ctx context.Context, c client.Client, namespace string, name string) (*corev1.ConfigMap, string, error) {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, cm)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash, err := ComputeInputHash(ConfigMapHashSource(cm))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return cm, hash, nil
+}
+
+// GetConfigMapsWithOptions - get the ConfigMaps and return the hash of the data
+// of every ConfigMap found, keyed by name. Missing optional ConfigMaps are
+// skipped, all missing required ConfigMaps are reported at once with a
+// *MissingConfigMapsError, together with the hashes of the ConfigMaps found.
+func GetConfigMapsWithOptions(ctx context.Context, c client.Client, namespace string, refs []ConfigMapRef) (map[string]string, error) {
+	hashes := map[string]string{}
+	missing := []string{}
+
+	for _, ref := range refs {
+		_, hash, err := GetConfigMap(ctx, c, namespace, ref.Name)
+		if err != nil {
+			if k8s_errors.IsNotFound(err) {
+				if !ref.Optional {
+					missing = append(missing, ref.Name)
+				}
+				continue
+			}
+			return nil, err
+		}
+		hashes[ref.Name] = hash
+	}
+
+	if len(missing) > 0 {
+		return hashes, &MissingConfigMapsError{Namespace: namespace, Names: missing}
+	}
+
+	return hashes, nil
+}
+
+// PruneConfigMaps - delete the ConfigMaps in the namespace of the owner
+// matching the labels which are not listed in keep. keep has to be the full
+// set of ConfigMap names the owner currently wants, i.e. all names just
+// ensured, as everything else gets deleted. Only ConfigMaps controlled by the
+// owner get deleted, so unrelated ConfigMaps carrying the same labels are
+// never touched. If the owner is deletion protected and force is not set,
+// nothing gets deleted and an error wrapping ErrDeletionProtected is returned.
+// Returns the names of the deleted ConfigMaps.
+func PruneConfigMaps(
+	ctx context.Context,
+	c client.Client,
+	owner metav1.Object,
+	labels map[string]string,
+	keep []string,
+	force bool,
+) ([]string, error) {
+	configMaps := &corev1.ConfigMapList{}
+	err := c.List(ctx, configMaps, client.InNamespace(owner.GetNamespace()), client.MatchingLabels(labels))
+	if err != nil {
+		return nil, err
+	}
+
+	keepNames := map[string]bool{}
+	for _, name := range keep {
+		keepNames[name] = true
+	}
+
+	deleted := []string{}
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		if keepNames[cm.Name] {
+			continue
+		}
+		ref, ok := GetControllerOwnerRef(cm)
+		if !ok || ref.UID != owner.GetUID() {
+			continue
+		}
+		if err := CheckDeletionProtection(owner, force); err != nil {
+			return deleted, err
+		}
+
+		err := c.Delete(ctx, cm)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return deleted, err
+		}
+		deleted = append(deleted, cm.Name)
+	}
+
+	return deleted, nil
+}
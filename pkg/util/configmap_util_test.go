@@ -0,0 +1,104 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetConfigMapsWithOptions(t *testing.T) {
+	c := fake.NewFakeClient(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "present", Namespace: "test"},
+		Data:       map[string]string{"key": "value"},
+	})
+
+	// mixed missing and present
+	hashes, err := GetConfigMapsWithOptions(context.TODO(), c, "test", []ConfigMapRef{
+		{Name: "missing1"},
+		{Name: "present"},
+		{Name: "optional", Optional: true},
+		{Name: "missing2"},
+	})
+	missingErr, ok := err.(*MissingConfigMapsError)
+	if !ok || !reflect.DeepEqual(missingErr.Names, []string{"missing1", "missing2"}) {
+		t.Errorf("Expected missing missing1 and missing2; Got: %v", err)
+	}
+	if _, ok := hashes["present"]; !ok || len(hashes) != 1 {
+		t.Errorf("Expected hash of present ConfigMap; Got: %v", hashes)
+	}
+
+	// all optional
+	hashes, err = GetConfigMapsWithOptions(context.TODO(), c, "test", []ConfigMapRef{
+		{Name: "optional1", Optional: true},
+		{Name: "present", Optional: true},
+	})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, ok := hashes["present"]; !ok || len(hashes) != 1 {
+		t.Errorf("Expected hash of present ConfigMap; Got: %v", hashes)
+	}
+}
+
+func TestGetConfigMapFromOtherNamespace(t *testing.T) {
+	c := fake.NewFakeClient(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "other"},
+		Data:       map[string]string{"key": "value"},
+	})
+
+	cm, hash, err := GetConfigMap(context.TODO(), c, "other", "shared")
+	if err != nil || cm.Data["key"] != "value" || hash == "" {
+		t.Errorf("Expected ConfigMap from namespace other; Got: %v, %s, %v", cm, hash, err)
+	}
+	if _, _, err := GetConfigMap(context.TODO(), c, "test", "shared"); err == nil {
+		t.Errorf("Didn't expect ConfigMap in namespace test")
+	}
+}
+
+func TestPruneConfigMaps(t *testing.T) {
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "test", UID: "owner-uid"}}
+	labels := map[string]string{"app": "nova"}
+	controller := true
+	owned := func(name string, uid types.UID) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: labels}}
+		if uid != "" {
+			cm.OwnerReferences = []metav1.OwnerReference{{Name: "owner", UID: uid, Controller: &controller}}
+		}
+		return cm
+	}
+	c := fake.NewFakeClient(
+		owned("nova-scripts", "owner-uid"),
+		owned("nova-stale", "owner-uid"),
+		owned("other-owner", "other-uid"),
+		owned("unowned", ""),
+	)
+
+	protected := owner.DeepCopy()
+	protected.Annotations = map[string]string{DeletionProtectionAnnotation: "true"}
+	deleted, err := PruneConfigMaps(context.TODO(), c, protected, labels, []string{"nova-scripts"}, false)
+	if !errors.Is(err, ErrDeletionProtected) || len(deleted) != 0 {
+		t.Errorf("Expected deletion protected error; Got: %v, %v", deleted, err)
+	}
+
+	deleted, err = PruneConfigMaps(context.TODO(), c, protected, labels, []string{"nova-scripts"}, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(deleted, []string{"nova-stale"}) {
+		t.Errorf("Expected: [nova-stale]; Got: %v", deleted)
+	}
+
+	remaining := &corev1.ConfigMapList{}
+	if err := c.List(context.TODO(), remaining); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(remaining.Items) != 3 {
+		t.Errorf("Expected 3 remaining ConfigMaps; Got: %v", remaining.Items)
+	}
+}
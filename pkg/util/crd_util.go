@@ -0,0 +1,129 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+Optional integrations with CRDs of other operators:
+
+res, err := util.WaitForCRD(mgr.GetRESTMapper(), rabbitmqGVK, time.Second*30)
+if err != nil || res.RequeueAfter > 0 {
+	return res, err
+}
+
+To reconcile again as soon as the CRD gets installed, watch CRDs using
+util.CRDObject() filtered with util.CRDPredicate(rabbitmqGVK).
+*/
+
+// CRDGroupVersionKind - GVK of CustomResourceDefinition
+var CRDGroupVersionKind = schema.GroupVersionKind{
+	Group:   "apiextensions.k8s.io",
+	Version: "v1",
+	Kind:    "CustomResourceDefinition",
+}
+
+// resettableRESTMapper - RESTMapper which caches the discovery information
+type resettableRESTMapper interface {
+	Reset()
+}
+
+// CRDExists - returns true if the API server serves the kind. When the
+// kind is unknown and the mapper caches discovery information, the cache is
+// invalidated and the lookup retried once.
+func CRDExists(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (bool, error) {
+	exists, err := kindExists(mapper, gvk)
+	if err != nil || exists {
+		return exists, err
+	}
+
+	if r, ok := mapper.(resettableRESTMapper); ok {
+		r.Reset()
+		return kindExists(mapper, gvk)
+	}
+
+	return false, nil
+}
+
+func kindExists(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (bool, error) {
+	_, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// WaitForCRD - returns a result requeueing after requeueAfter while the kind
+// is not served by the API server
+func WaitForCRD(mapper meta.RESTMapper, gvk schema.GroupVersionKind, requeueAfter time.Duration) (reconcile.Result, error) {
+	exists, err := CRDExists(mapper, gvk)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !exists {
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// CRDObject - CustomResourceDefinition object to be used as watch source
+func CRDObject() *unstructured.Unstructured {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(CRDGroupVersionKind)
+	return crd
+}
+
+// CRDPredicate - predicate passing create events of the CRD defining the kind
+func CRDPredicate(gvk schema.GroupVersionKind) predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return crdDefinesKind(e.Object, gvk)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return false
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+func crdDefinesKind(obj runtime.Object, gvk schema.GroupVersionKind) bool {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+	group, _, _ := unstructured.NestedString(u.Object, "spec", "group")
+	kind, _, _ := unstructured.NestedString(u.Object, "spec", "names", "kind")
+	return group == gvk.Group && kind == gvk.Kind
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var testGVK = schema.GroupVersionKind{Group: "rabbitmq.com", Version: "v1beta1", Kind: "RabbitmqCluster"}
+
+// resettingMapper - mapper learning about the kind once its cache got reset
+type resettingMapper struct {
+	*meta.DefaultRESTMapper
+	installed bool
+}
+
+func (m *resettingMapper) Reset() {
+	if m.installed {
+		m.Add(testGVK, meta.RESTScopeNamespace)
+	}
+}
+
+func TestWaitForCRD(t *testing.T) {
+	mapper := &resettingMapper{DefaultRESTMapper: meta.NewDefaultRESTMapper(nil)}
+
+	res, err := WaitForCRD(mapper, testGVK, time.Second)
+	if err != nil || res.RequeueAfter != time.Second {
+		t.Errorf("Expected requeue for missing CRD; Got: %v, %v", res, err)
+	}
+
+	// CRD gets installed
+	mapper.installed = true
+	res, err = WaitForCRD(mapper, testGVK, time.Second)
+	if err != nil || res.RequeueAfter != 0 {
+		t.Errorf("Expected no requeue for existing CRD; Got: %v, %v", res, err)
+	}
+}
+
+func TestCRDPredicate(t *testing.T) {
+	crd := CRDObject()
+	_ = unstructured.SetNestedField(crd.Object, "rabbitmq.com", "spec", "group")
+	_ = unstructured.SetNestedField(crd.Object, "RabbitmqCluster", "spec", "names", "kind")
+	other := CRDObject()
+	_ = unstructured.SetNestedField(other.Object, "other.com", "spec", "group")
+
+	p := CRDPredicate(testGVK)
+	if !p.Create(event.CreateEvent{Meta: crd, Object: crd}) {
+		t.Errorf("Expected create event of CRD to pass")
+	}
+	if p.Create(event.CreateEvent{Meta: other, Object: other}) {
+		t.Errorf("Didn't expect create event of other CRD to pass")
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+In controller defaulting for CRs which can not use a defaulting webhook:
+
+changed, err := util.ApplyDefaultsOnce(ctx, r.Client, instance, func(obj runtime.Object) bool {
+	o := obj.(*v1.Nova)
+	return util.DefaultString(&o.Spec.ContainerImage, defaultImage)
+})
+
+Defaults are only applied when the defaults hash annotation is missing or
+the defaults changed with an operator upgrade, so fields the user cleared
+later are not defaulted again.
+*/
+
+const (
+	// DefaultsHashAnnotation - annotation recording the hash of the applied defaults
+	DefaultsHashAnnotation = AnnotationPrefix + "/defaults-hash"
+)
+
+// DefaultsFunc - applies defaults to the object, returns true if it changed the object
+type DefaultsFunc func(obj runtime.Object) bool
+
+// DefaultString - set the field to value if it is empty, returns true if it got set
+func DefaultString(field *string, value string) bool {
+	if *field != "" {
+		return false
+	}
+	*field = value
+	return true
+}
+
+// DefaultInt32 - set the field to value if it is zero, returns true if it got set
+func DefaultInt32(field *int32, value int32) bool {
+	if *field != 0 {
+		return false
+	}
+	*field = value
+	return true
+}
+
+// DefaultInt32Ptr - set the field to value if it is nil, returns true if it got set
+func DefaultInt32Ptr(field **int32, value int32) bool {
+	if *field != nil {
+		return false
+	}
+	*field = &value
+	return true
+}
+
+// ApplyDefaultsOnce - apply the defaults to the object and patch it, if the
+// defaults hash annotation is missing or differs from the hash of the current
+// defaults. Returns true if the object got patched.
+func ApplyDefaultsOnce(ctx context.Context, c client.Client, obj runtime.Object, defaults DefaultsFunc) (bool, error) {
+	hash, err := defaultsHash(obj, defaults)
+	if err != nil {
+		return false, err
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	if accessor.GetAnnotations()[DefaultsHashAnnotation] == hash {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject())
+	defaults(obj)
+
+	annotations := accessor.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[DefaultsHashAnnotation] = hash
+	accessor.SetAnnotations(annotations)
+
+	if err := c.Patch(ctx, obj, patch); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// defaultsHash - hash of the defaults applied to an empty object of the same type
+func defaultsHash(obj runtime.Object, defaults DefaultsFunc) (string, error) {
+	empty := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+	defaults(empty)
+	return ObjectHash(empty)
+}
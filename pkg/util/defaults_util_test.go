@@ -0,0 +1,73 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func configMapDefaults(value string) DefaultsFunc {
+	return func(obj runtime.Object) bool {
+		cm := obj.(*corev1.ConfigMap)
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		v := cm.Data["key"]
+		changed := DefaultString(&v, value)
+		cm.Data["key"] = v
+		return changed
+	}
+}
+
+func TestApplyDefaultsOnce(t *testing.T) {
+	c := fake.NewFakeClient(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "test"},
+	})
+	key := types.NamespacedName{Name: "cm", Namespace: "test"}
+
+	get := func() *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(context.TODO(), key, cm); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return cm
+	}
+
+	// first apply
+	changed, err := ApplyDefaultsOnce(context.TODO(), c, get(), configMapDefaults("v1"))
+	if err != nil || !changed {
+		t.Fatalf("Expected defaults to be applied; Got changed: %v, err: %v", changed, err)
+	}
+	if get().Data["key"] != "v1" {
+		t.Errorf("Expected default v1; Got: %v", get().Data)
+	}
+
+	// user clears the defaulted value, which must not be defaulted again
+	cm := get()
+	cm.Data["key"] = ""
+	if err := c.Update(context.TODO(), cm); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	changed, err = ApplyDefaultsOnce(context.TODO(), c, get(), configMapDefaults("v1"))
+	if err != nil || changed {
+		t.Errorf("Expected no reapply; Got changed: %v, err: %v", changed, err)
+	}
+	if get().Data["key"] != "" {
+		t.Errorf("Expected user value to be kept; Got: %v", get().Data)
+	}
+
+	// upgrade with new defaults
+	oldHash := get().Annotations[DefaultsHashAnnotation]
+	changed, err = ApplyDefaultsOnce(context.TODO(), c, get(), configMapDefaults("v2"))
+	if err != nil || !changed {
+		t.Fatalf("Expected new defaults to be applied; Got changed: %v, err: %v", changed, err)
+	}
+	if get().Data["key"] != "v2" || get().Annotations[DefaultsHashAnnotation] == oldHash {
+		t.Errorf("Expected default v2 and a new defaults hash; Got: %v", get())
+	}
+}
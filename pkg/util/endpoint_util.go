@@ -0,0 +1,148 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const (
+	// DefaultClusterDomain - default DNS domain of the cluster
+	DefaultClusterDomain = "cluster.local"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// SetStatusEndpoints - set the endpoint URLs, mapping interface -> URL, in the
+// status map. URLs get normalized, interfaces not in endpointMap get removed.
+// Returns true if the status map changed.
+func SetStatusEndpoints(status *map[string]string, endpointMap map[string]string) bool {
+	updated := map[string]string{}
+	for endpointInterface, u := range endpointMap {
+		updated[endpointInterface] = NormalizeEndpointURL(u)
+	}
+
+	changed := len(updated) != len(*status)
+	if !changed {
+		for k, v := range updated {
+			if cur, ok := (*status)[k]; !ok || cur != v {
+				changed = true
+				break
+			}
+		}
+	}
+
+	if changed {
+		*status = updated
+	}
+	return changed
+}
+
+// NormalizeEndpointURL - lowercase scheme and host and strip the default port
+// of the scheme. URLs which can not be parsed are returned unchanged.
+func NormalizeEndpointURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return u
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Hostname())
+	port := parsed.Port()
+	if port == "" || port == defaultPorts[parsed.Scheme] {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		parsed.Host = host
+	} else {
+		parsed.Host = net.JoinHostPort(host, port)
+	}
+
+	return parsed.String()
+}
+
+// ValidateEndpointURL - returns an error if the URL is not an absolute URL with a host
+func ValidateEndpointURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("Invalid endpoint URL %s: %v", u, err)
+	}
+	if !parsed.IsAbs() || parsed.Host == "" {
+		return fmt.Errorf("Endpoint URL %s is not an absolute URL", u)
+	}
+	return nil
+}
+
+// GetHostnamesForSANs - returns the deduplicated list of names a certificate
+// for the service needs, in the order: service, service.namespace,
+// service.namespace.svc, service.namespace.svc.<clusterDomain>, the route host
+// and the external hostnames/IPs, e.g. of a LoadBalancer. An empty
+// clusterDomain defaults to cluster.local.
+func GetHostnamesForSANs(
+	serviceName string,
+	namespace string,
+	clusterDomain string,
+	routeHost *string,
+	externalHostnames []string,
+) []string {
+	if clusterDomain == "" {
+		clusterDomain = DefaultClusterDomain
+	}
+
+	names := []string{
+		serviceName,
+		fmt.Sprintf("%s.%s", serviceName, namespace),
+		fmt.Sprintf("%s.%s.svc", serviceName, namespace),
+		fmt.Sprintf("%s.%s.svc.%s", serviceName, namespace, strings.TrimSuffix(clusterDomain, ".")),
+	}
+	if routeHost != nil && *routeHost != "" {
+		names = append(names, *routeHost)
+	}
+	names = append(names, externalHostnames...)
+
+	seen := map[string]bool{}
+	sans := []string{}
+	for _, n := range names {
+		n = strings.ToLower(n)
+		if !seen[n] {
+			seen[n] = true
+			sans = append(sans, n)
+		}
+	}
+	return sans
+}
+
+// SplitSANs - split the names into DNS names and IP addresses, for the
+// DNSNames and IPAddresses of a certificate
+func SplitSANs(names []string) ([]string, []net.IP) {
+	dnsNames := []string{}
+	ips := []net.IP{}
+	for _, n := range names {
+		if ip := net.ParseIP(n); ip != nil {
+			ips = append(ips, ip)
+		} else {
+			dnsNames = append(dnsNames, n)
+		}
+	}
+	return dnsNames, ips
+}
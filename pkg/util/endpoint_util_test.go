@@ -0,0 +1,114 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeEndpointURL(t *testing.T) {
+	for u, expected := range map[string]string{
+		"HTTP://Nova.Test.SVC:80/v2.1":  "http://nova.test.svc/v2.1",
+		"https://nova.test.svc:443":     "https://nova.test.svc",
+		"https://nova.test.svc:8774/v2": "https://nova.test.svc:8774/v2",
+		"http://[FD00::1]:80/":          "http://[fd00::1]/",
+		"http://[fd00::1]:8774":         "http://[fd00::1]:8774",
+		"not a url":                     "not a url",
+	} {
+		if normalized := NormalizeEndpointURL(u); normalized != expected {
+			t.Errorf("Normalizing %s; Expected: %s; Got: %s", u, expected, normalized)
+		}
+	}
+}
+
+func TestSetStatusEndpoints(t *testing.T) {
+	status := map[string]string{}
+
+	changed := SetStatusEndpoints(&status, map[string]string{
+		"public":   "HTTPS://nova.example.com:443",
+		"internal": "http://nova.test.svc:8774",
+	})
+	expected := map[string]string{
+		"public":   "https://nova.example.com",
+		"internal": "http://nova.test.svc:8774",
+	}
+	if !changed || !reflect.DeepEqual(status, expected) {
+		t.Errorf("Expected changed status %v; Got: %v, %v", expected, changed, status)
+	}
+
+	changed = SetStatusEndpoints(&status, map[string]string{
+		"public":   "https://nova.example.com",
+		"internal": "http://nova.test.svc:8774",
+	})
+	if changed {
+		t.Errorf("Didn't expect a change for equal normalized endpoints")
+	}
+
+	// prune internal
+	changed = SetStatusEndpoints(&status, map[string]string{
+		"public": "https://nova.example.com",
+	})
+	expected = map[string]string{"public": "https://nova.example.com"}
+	if !changed || !reflect.DeepEqual(status, expected) {
+		t.Errorf("Expected pruned status %v; Got: %v, %v", expected, changed, status)
+	}
+}
+
+func TestValidateEndpointURL(t *testing.T) {
+	for u, valid := range map[string]bool{
+		"https://nova.example.com/v2.1": true,
+		"nova.example.com":              false,
+		"/v2.1":                         false,
+		"http://":                       false,
+	} {
+		err := ValidateEndpointURL(u)
+		if valid && err != nil {
+			t.Errorf("Unexpected error validating %s: %v", u, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("Didn't get expected error validating %s", u)
+		}
+	}
+}
+
+func TestGetHostnamesForSANs(t *testing.T) {
+	route := "nova-public.apps.example.com"
+	empty := ""
+
+	tests := []struct {
+		clusterDomain string
+		routeHost     *string
+		external      []string
+		expected      []string
+	}{
+		{
+			"", nil, nil,
+			[]string{"nova", "nova.test", "nova.test.svc", "nova.test.svc.cluster.local"},
+		},
+		{
+			"example.org.", &empty, nil,
+			[]string{"nova", "nova.test", "nova.test.svc", "nova.test.svc.example.org"},
+		},
+		{
+			"", &route, []string{"192.168.1.10", "NOVA.test.svc", "lb.example.com"},
+			[]string{"nova", "nova.test", "nova.test.svc", "nova.test.svc.cluster.local",
+				"nova-public.apps.example.com", "192.168.1.10", "lb.example.com"},
+		},
+	}
+
+	for _, test := range tests {
+		sans := GetHostnamesForSANs("nova", "test", test.clusterDomain, test.routeHost, test.external)
+		if !reflect.DeepEqual(sans, test.expected) {
+			t.Errorf("Expected: %v; Got: %v", test.expected, sans)
+		}
+	}
+}
+
+func TestSplitSANs(t *testing.T) {
+	dnsNames, ips := SplitSANs([]string{"nova", "192.168.1.10", "fd00::1"})
+	if !reflect.DeepEqual(dnsNames, []string{"nova"}) {
+		t.Errorf("Unexpected DNS names: %v", dnsNames)
+	}
+	if len(ips) != 2 || ips[0].String() != "192.168.1.10" || ips[1].String() != "fd00::1" {
+		t.Errorf("Unexpected IPs: %v", ips)
+	}
+}
@@ -72,3 +72,22 @@ func EnvValue(value string) EnvSetter {
 		env.ValueFrom = nil
 	}
 }
+
+// EnsureEnvFromSecret - add an envFrom reference to the secret to the
+// container, exposing all its keys as env vars with the optional prefix
+func EnsureEnvFromSecret(container *corev1.Container, secretName string, prefix string) {
+	envFrom := corev1.EnvFromSource{
+		Prefix: prefix,
+		SecretRef: &corev1.SecretEnvSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+		},
+	}
+
+	for i, e := range container.EnvFrom {
+		if e.SecretRef != nil && e.SecretRef.Name == secretName {
+			container.EnvFrom[i] = envFrom
+			return
+		}
+	}
+	container.EnvFrom = append(container.EnvFrom, envFrom)
+}
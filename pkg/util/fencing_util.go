@@ -0,0 +1,108 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+Prevent two operator instances, e.g. old and new during an OLM upgrade, from
+reconciling the same CR:
+
+owned, res, err := util.ClaimReconcileOwnership(ctx, r.Client, instance, os.Getenv("POD_NAME"), time.Minute)
+if err != nil || !owned {
+	return res, err
+}
+*/
+
+const (
+	// ReconcileOwnerAnnotation - annotation recording the operator instance reconciling the CR
+	ReconcileOwnerAnnotation = AnnotationPrefix + "/reconcile-owner"
+)
+
+// reconcileClaim - content of the ReconcileOwnerAnnotation
+type reconcileClaim struct {
+	Identity  string    `json:"identity"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+// ClaimReconcileOwnership - claim the CR for the operator instance with the
+// identity. Returns false and a result requeueing once the claim expires if
+// another instance holds a claim whose heartbeat is younger than ttl. A
+// stale claim gets taken over. The own heartbeat is refreshed when it is
+// older than half the ttl, so not every reconcile patches the CR.
+// The claim is patched with the resourceVersion of obj as precondition, so
+// of two instances taking over a stale claim at the same time only one wins,
+// the other one gets false and a requeue.
+func ClaimReconcileOwnership(
+	ctx context.Context,
+	c client.Client,
+	obj runtime.Object,
+	identity string,
+	ttl time.Duration,
+) (bool, reconcile.Result, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, reconcile.Result{}, err
+	}
+
+	now := time.Now()
+	claim := reconcileClaim{}
+	if v, ok := accessor.GetAnnotations()[ReconcileOwnerAnnotation]; ok {
+		// an invalid claim is treated like a stale one and taken over
+		_ = json.Unmarshal([]byte(v), &claim)
+	}
+
+	age := now.Sub(claim.Heartbeat)
+	switch {
+	case claim.Identity != identity && claim.Identity != "" && age < ttl:
+		return false, reconcile.Result{RequeueAfter: ttl - age}, nil
+	case claim.Identity == identity && age < ttl/2:
+		return true, reconcile.Result{}, nil
+	}
+
+	v, err := json.Marshal(reconcileClaim{Identity: identity, Heartbeat: now})
+	if err != nil {
+		return false, reconcile.Result{}, err
+	}
+
+	patch := client.MergeFromWithOptions(obj.DeepCopyObject(), client.MergeFromWithOptimisticLock{})
+	annotations := accessor.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ReconcileOwnerAnnotation] = string(v)
+	accessor.SetAnnotations(annotations)
+
+	if err := c.Patch(ctx, obj, patch); err != nil {
+		if k8s_errors.IsConflict(err) {
+			return false, reconcile.Result{Requeue: true}, nil
+		}
+		return false, reconcile.Result{}, err
+	}
+
+	return true, reconcile.Result{}, nil
+}
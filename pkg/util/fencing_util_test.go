@@ -0,0 +1,105 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func claimedConfigMap(t *testing.T, identity string, heartbeat time.Time) *corev1.ConfigMap {
+	v, err := json.Marshal(reconcileClaim{Identity: identity, Heartbeat: heartbeat})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "cr",
+			Namespace:       "test",
+			ResourceVersion: "1",
+			Annotations:     map[string]string{ReconcileOwnerAnnotation: string(v)},
+		},
+	}
+}
+
+func TestClaimReconcileOwnership(t *testing.T) {
+	ttl := time.Minute
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		obj      *corev1.ConfigMap
+		owned    bool
+		identity string
+	}{
+		{"unclaimed", &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cr", Namespace: "test", ResourceVersion: "1"}}, true, "new"},
+		{"active competing claim", claimedConfigMap(t, "old", now.Add(-10*time.Second)), false, "old"},
+		{"stale competing claim", claimedConfigMap(t, "old", now.Add(-2*ttl)), true, "new"},
+		{"own claim", claimedConfigMap(t, "new", now.Add(-10*time.Second)), true, "new"},
+	}
+
+	for _, test := range tests {
+		c := fake.NewFakeClient(test.obj)
+		obj := &corev1.ConfigMap{}
+		key := types.NamespacedName{Name: "cr", Namespace: "test"}
+		if err := c.Get(context.TODO(), key, obj); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		owned, res, err := ClaimReconcileOwnership(context.TODO(), c, obj, "new", ttl)
+		if err != nil {
+			t.Fatalf("%s: Unexpected error: %v", test.name, err)
+		}
+		if owned != test.owned || (res.RequeueAfter > 0) == test.owned {
+			t.Errorf("%s: Expected owned: %v; Got: %v, %v", test.name, test.owned, owned, res)
+		}
+
+		if err := c.Get(context.TODO(), key, obj); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		claim := reconcileClaim{}
+		_ = json.Unmarshal([]byte(obj.Annotations[ReconcileOwnerAnnotation]), &claim)
+		if claim.Identity != test.identity {
+			t.Errorf("%s: Expected claim of %s; Got: %v", test.name, test.identity, claim)
+		}
+	}
+}
+
+func TestClaimReconcileOwnershipRace(t *testing.T) {
+	ttl := time.Minute
+	c := fake.NewFakeClient(claimedConfigMap(t, "old", time.Now().Add(-2*ttl)))
+	key := types.NamespacedName{Name: "cr", Namespace: "test"}
+
+	// both instances see the same stale claim
+	first := &corev1.ConfigMap{}
+	second := &corev1.ConfigMap{}
+	for _, obj := range []*corev1.ConfigMap{first, second} {
+		if err := c.Get(context.TODO(), key, obj); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	owned, _, err := ClaimReconcileOwnership(context.TODO(), c, first, "first", ttl)
+	if err != nil || !owned {
+		t.Fatalf("Expected first claimer to win; Got: %v, %v", owned, err)
+	}
+	owned, res, err := ClaimReconcileOwnership(context.TODO(), c, second, "second", ttl)
+	if err != nil || owned || !res.Requeue {
+		t.Errorf("Expected second claimer to lose and requeue; Got: %v, %v, %v", owned, res, err)
+	}
+
+	obj := &corev1.ConfigMap{}
+	if err := c.Get(context.TODO(), key, obj); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	claim := reconcileClaim{}
+	_ = json.Unmarshal([]byte(obj.Annotations[ReconcileOwnerAnnotation]), &claim)
+	if claim.Identity != "first" {
+		t.Errorf("Expected claim of first; Got: %v", claim)
+	}
+}
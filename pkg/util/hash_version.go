@@ -0,0 +1,103 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+Versioned hashes allow changing how a hash gets computed without restarting
+every workload on operator upgrade:
+
+stored := deployment.Spec.Template.Annotations[key]
+if !util.HashMatches(stored, newHash, oldHash) {
+	// restart and store util.VersionedHash(util.HashV2, newHash)
+}
+
+While HASH_COMPAT_V1 is set to "true", an unprefixed v1 hash still matches
+when it equals the hash computed the old way. ObjectsWithLegacyHash reports
+the objects still carrying v1 hashes, so compatibility can be dropped once
+none are left.
+*/
+
+// HashVersion - version of the algorithm a hash got computed with
+type HashVersion string
+
+const (
+	// HashV1 - legacy hashes, stored without version prefix
+	HashV1 HashVersion = "v1"
+	// HashV2 - hashes stored as "v2:<hash>"
+	HashV2 HashVersion = "v2"
+
+	// HashCompatEnv - env var enabling the v1 hash compatibility grace period
+	HashCompatEnv = "HASH_COMPAT_V1"
+)
+
+// VersionedHash - returns the hash in its stored format for the version
+func VersionedHash(version HashVersion, hash string) string {
+	if version == HashV1 {
+		return hash
+	}
+	return string(version) + ":" + hash
+}
+
+// ParseVersionedHash - returns the version and the hash of a stored hash
+func ParseVersionedHash(stored string) (HashVersion, string) {
+	if i := strings.Index(stored, ":"); i > 0 {
+		return HashVersion(stored[:i]), stored[i+1:]
+	}
+	return HashV1, stored
+}
+
+// HashCompatEnabled - returns true during the v1 hash compatibility grace period
+func HashCompatEnabled() bool {
+	return os.Getenv(HashCompatEnv) == "true"
+}
+
+// HashMatches - returns true if the stored hash equals the current v2 hash,
+// or, during the compatibility grace period, if it is a v1 hash equal to the
+// hash computed the legacy way
+func HashMatches(stored string, current string, legacy string) bool {
+	version, hash := ParseVersionedHash(stored)
+	switch version {
+	case HashV2:
+		return hash == current
+	case HashV1:
+		return HashCompatEnabled() && legacy != "" && hash == legacy
+	}
+	return false
+}
+
+// ObjectsWithLegacyHash - returns the namespace/name of all objects carrying
+// a v1 hash in the annotation
+func ObjectsWithLegacyHash(objs []metav1.Object, annotationKey string) []string {
+	names := []string{}
+	for _, obj := range objs {
+		stored, ok := obj.GetAnnotations()[annotationKey]
+		if !ok {
+			continue
+		}
+		if version, _ := ParseVersionedHash(stored); version == HashV1 {
+			names = append(names, obj.GetNamespace()+"/"+obj.GetName())
+		}
+	}
+	return names
+}
@@ -0,0 +1,51 @@
+package util
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHashMatches(t *testing.T) {
+	defer os.Unsetenv(HashCompatEnv)
+
+	tests := []struct {
+		stored   string
+		compat   bool
+		expected bool
+	}{
+		{VersionedHash(HashV2, "new"), false, true},
+		{VersionedHash(HashV2, "other"), false, false},
+		{VersionedHash(HashV1, "old"), false, false},
+		{VersionedHash(HashV1, "old"), true, true},
+		{VersionedHash(HashV1, "other"), true, false},
+		{"v3:new", true, false},
+	}
+
+	for _, test := range tests {
+		os.Setenv(HashCompatEnv, map[bool]string{true: "true", false: ""}[test.compat])
+		if m := HashMatches(test.stored, "new", "old"); m != test.expected {
+			t.Errorf("Stored %s, compat %v; Expected: %v; Got: %v", test.stored, test.compat, test.expected, m)
+		}
+	}
+}
+
+func TestObjectsWithLegacyHash(t *testing.T) {
+	key := HashAnnotationKey("config")
+	cm := func(name string, annotations map[string]string) metav1.Object {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Annotations: annotations}}
+	}
+
+	names := ObjectsWithLegacyHash([]metav1.Object{
+		cm("v1", map[string]string{key: VersionedHash(HashV1, "old")}),
+		cm("v2", map[string]string{key: VersionedHash(HashV2, "new")}),
+		cm("none", nil),
+	}, key)
+
+	if !reflect.DeepEqual(names, []string{"test/v1"}) {
+		t.Errorf("Expected: [test/v1]; Got: %v", names)
+	}
+}
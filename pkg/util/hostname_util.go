@@ -0,0 +1,115 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// SplitHostPortDefault - split a user provided host[:port] into host and port,
+// using defaultPort when no port is given. IPv6 literals can be passed with
+// or without brackets, with a port they require brackets. The returned
+// host has no brackets.
+func SplitHostPortDefault(s string, defaultPort int32) (string, int32, error) {
+	// bare IPv6 literal without port
+	if ip := net.ParseIP(s); ip != nil {
+		return s, defaultPort, nil
+	}
+	// bracketed IPv6 literal without port
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		host := s[1 : len(s)-1]
+		if !isIPv6(host) {
+			return "", 0, fmt.Errorf("brackets are only allowed around an IPv6 address: %s", s)
+		}
+		return host, defaultPort, nil
+	}
+	if !strings.Contains(s, ":") {
+		return s, defaultPort, nil
+	}
+
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		return "", 0, err
+	}
+	if strings.HasPrefix(s, "[") && !isIPv6(host) {
+		return "", 0, fmt.Errorf("brackets are only allowed around an IPv6 address: %s", s)
+	}
+	port, err := strconv.ParseInt(portStr, 10, 32)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %s", portStr)
+	}
+
+	return host, int32(port), nil
+}
+
+func isIPv6(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() == nil
+}
+
+// ValidateHostname - validate a user provided hostname, e.g. a database host
+// or endpoint override. Uppercase characters and a trailing dot are accepted.
+// A port is only accepted with allowPort, IP addresses only with allowIP.
+func ValidateHostname(s string, allowPort bool, allowIP bool) error {
+	if s == "" {
+		return fmt.Errorf("hostname must not be empty")
+	}
+	if strings.Contains(s, "://") {
+		return fmt.Errorf("hostname %s must not contain a scheme", s)
+	}
+	if strings.ContainsAny(s, "/?#") {
+		return fmt.Errorf("hostname %s must not contain a path", s)
+	}
+
+	host, port, err := SplitHostPortDefault(s, 0)
+	if err != nil {
+		return fmt.Errorf("invalid hostname %s: %v", s, err)
+	}
+	if port != 0 && !allowPort {
+		return fmt.Errorf("hostname %s must not contain a port", s)
+	}
+
+	if net.ParseIP(host) != nil {
+		if !allowIP {
+			return fmt.Errorf("hostname %s must not be an IP address", s)
+		}
+		return nil
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid hostname %s: %s", s, strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// ValidateHostnameField - ValidateHostname returning a field error list, to
+// be used in validating webhooks
+func ValidateHostnameField(fldPath *field.Path, s string, allowPort bool, allowIP bool) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if err := ValidateHostname(s, allowPort, allowIP); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath, s, err.Error()))
+	}
+	return allErrs
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestSplitHostPortDefault(t *testing.T) {
+	tests := []struct {
+		s    string
+		host string
+		port int32
+		err  bool
+	}{
+		{"db.example.com", "db.example.com", 3306, false},
+		{"db.example.com:3307", "db.example.com", 3307, false},
+		{"192.168.0.1:3307", "192.168.0.1", 3307, false},
+		{"fd00::1", "fd00::1", 3306, false},
+		{"[fd00::1]", "fd00::1", 3306, false},
+		{"[fd00::1]:3307", "fd00::1", 3307, false},
+		{"db.example.com:port", "", 0, true},
+		{"db.example.com:70000", "", 0, true},
+		{"[db.example.com]", "", 0, true},
+		{"[db.example.com]:3307", "", 0, true},
+		{"[192.168.0.1]", "", 0, true},
+	}
+
+	for _, test := range tests {
+		host, port, err := SplitHostPortDefault(test.s, 3306)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("Didn't get expected error splitting %s", test.s)
+		case !test.err && err != nil:
+			t.Errorf("Unexpected error splitting %s: %v", test.s, err)
+		case !test.err && (host != test.host || port != test.port):
+			t.Errorf("Splitting %s; Expected: %s %d; Got: %s %d", test.s, test.host, test.port, host, port)
+		}
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	tests := []struct {
+		s         string
+		allowPort bool
+		allowIP   bool
+		valid     bool
+	}{
+		{"db.example.com", false, false, true},
+		{"DB.Example.COM", false, false, true},
+		{"db.example.com.", false, false, true},
+		{"db.example.com:3306", false, false, false},
+		{"db.example.com:3306", true, false, true},
+		{"mysql://db.example.com", true, true, false},
+		{"db.example.com/path", true, true, false},
+		{"db_host", true, true, false},
+		{"192.168.0.1", false, false, false},
+		{"192.168.0.1", false, true, true},
+		{"fd00::1", false, true, true},
+		{"FD00::1", false, true, true},
+		{"[fd00::1]", false, true, true},
+		{"[fd00::1]:3306", false, true, false},
+		{"[fd00::1]:3306", true, true, true},
+		{"", true, true, false},
+	}
+
+	for _, test := range tests {
+		err := ValidateHostname(test.s, test.allowPort, test.allowIP)
+		if test.valid && err != nil {
+			t.Errorf("Unexpected error validating %s: %v", test.s, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Didn't get expected error validating %s", test.s)
+		}
+	}
+
+	errs := ValidateHostnameField(field.NewPath("spec", "databaseHostname"), "mysql://db", false, false)
+	if len(errs) != 1 || errs[0].Field != "spec.databaseHostname" {
+		t.Errorf("Unexpected field errors: %v", errs)
+	}
+}
@@ -18,6 +18,7 @@ package util
 
 import (
 	"context"
+	"fmt"
 
 	batchv1 "k8s.io/api/batch/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
@@ -31,6 +32,25 @@ import (
 	"errors"
 )
 
+const (
+	// jobHashSuffixLength - number of hex characters of the spec hash appended to the job name
+	jobHashSuffixLength = 10
+)
+
+// WithHashSuffixedName - append a short hash of the job spec to the job name,
+// so each distinct spec results in a new job object and the previous jobs
+// are kept for history instead of being deleted and recreated. The name gets
+// truncated so the result fits into maxNameLength, as the job name is used
+// as label value on its pods.
+func WithHashSuffixedName(job *batchv1.Job) error {
+	hash, err := shortHash(job.Spec, jobHashSuffixLength)
+	if err != nil {
+		return err
+	}
+	job.Name = fmt.Sprintf("%s-%s", truncateName(job.Name, maxNameLength-jobHashSuffixLength-1), hash)
+	return nil
+}
+
 // DeleteJob func
 // kclient required to properly cleanup the job depending pods with DeleteOptions
 func DeleteJob(job *batchv1.Job, kclient kubernetes.Interface, log logr.Logger) (bool, error) {
@@ -38,7 +58,7 @@ func DeleteJob(job *batchv1.Job, kclient kubernetes.Interface, log logr.Logger)
 	// Check if this Job already exists
 	foundJob, err := kclient.BatchV1().Jobs(job.Namespace).Get(context.TODO(), job.Name, metav1.GetOptions{})
 	if err == nil {
-		log.Info("Deleting Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		WithObject(log, foundJob).Info("Deleting Job")
 		background := metav1.DeletePropagationBackground
 		err = kclient.BatchV1().Jobs(foundJob.Namespace).Delete(context.TODO(), foundJob.Name, metav1.DeleteOptions{PropagationPolicy: &background})
 		if err != nil {
@@ -49,13 +69,26 @@ func DeleteJob(job *batchv1.Job, kclient kubernetes.Interface, log logr.Logger)
 	return false, nil
 }
 
+// DeleteJobIfNotProtected func
+// like DeleteJob, but returns ErrDeletionProtected without deleting the job
+// when the owner carries the deletion protection annotation, unless force is set
+func DeleteJobIfNotProtected(owner metav1.Object, job *batchv1.Job, kclient kubernetes.Interface, log logr.Logger, force bool) (bool, error) {
+	if err := CheckDeletionProtection(owner, force); err != nil {
+		WithObject(log, job).Info("Not deleting Job, owner is deletion protected")
+		return false, err
+	}
+	return DeleteJob(job, kclient, log)
+}
+
 // EnsureJob func
 func EnsureJob(job *batchv1.Job, client client.Client, log logr.Logger) (bool, error) {
+	log = WithObject(log, job)
+
 	// Check if this Job already exists
 	foundJob := &batchv1.Job{}
 	err := client.Get(context.TODO(), types.NamespacedName{Name: job.Name, Namespace: job.Namespace}, foundJob)
 	if err != nil && k8s_errors.IsNotFound(err) {
-		log.Info("Creating a new Job", "Job.Namespace", job.Namespace, "Job.Name", job.Name)
+		log.Info("Creating a new Job")
 		err = client.Create(context.TODO(), job)
 		if err != nil {
 			return false, err
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func jobWithParallelism(p int32) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-sync", Namespace: "test"},
+		Spec:       batchv1.JobSpec{Parallelism: &p},
+	}
+}
+
+func TestWithHashSuffixedName(t *testing.T) {
+	names := []string{}
+	for _, job := range []*batchv1.Job{jobWithParallelism(1), jobWithParallelism(1), jobWithParallelism(2)} {
+		if err := WithHashSuffixedName(job); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(job.Name, "db-sync-") || len(job.Name) != len("db-sync-")+jobHashSuffixLength {
+			t.Errorf("Unexpected job name: %s", job.Name)
+		}
+		names = append(names, job.Name)
+	}
+
+	if names[0] != names[1] {
+		t.Errorf("Expected same name for same spec; Got: %s and %s", names[0], names[1])
+	}
+	if names[0] == names[2] {
+		t.Errorf("Expected distinct names for distinct specs; Got: %s", names[0])
+	}
+
+	seen := map[string]int32{}
+	for i := int32(0); i < 1000; i++ {
+		job := jobWithParallelism(i)
+		if err := WithHashSuffixedName(job); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if other, ok := seen[job.Name]; ok {
+			t.Fatalf("Expected distinct names; Got: %s for parallelism %d and %d", job.Name, i, other)
+		}
+		seen[job.Name] = i
+	}
+
+	long := jobWithParallelism(1)
+	long.Name = strings.Repeat("a", 70)
+	if err := WithHashSuffixedName(long); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(long.Name) != maxNameLength {
+		t.Errorf("Expected length: %d; Got: %d (%s)", maxNameLength, len(long.Name), long.Name)
+	}
+}
+
+func TestDeleteJobIfNotProtected(t *testing.T) {
+	protected := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        "owner",
+		Annotations: map[string]string{DeletionProtectionAnnotation: "true"},
+	}}
+	log := logr.Logger(&capturingLogger{})
+
+	kclient := k8sfake.NewSimpleClientset(jobWithParallelism(1))
+	deleted, err := DeleteJobIfNotProtected(protected, jobWithParallelism(1), kclient, log, false)
+	if deleted || !errors.Is(err, ErrDeletionProtected) {
+		t.Errorf("Expected ErrDeletionProtected; Got: %v, %v", deleted, err)
+	}
+	if _, err := kclient.BatchV1().Jobs("test").Get(context.TODO(), "db-sync", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected protected job to still exist; Got: %v", err)
+	}
+
+	deleted, err = DeleteJobIfNotProtected(protected, jobWithParallelism(1), kclient, log, true)
+	if !deleted || err != nil {
+		t.Errorf("Expected forced delete; Got: %v, %v", deleted, err)
+	}
+
+	kclient = k8sfake.NewSimpleClientset(jobWithParallelism(1))
+	deleted, err = DeleteJobIfNotProtected(&corev1.ConfigMap{}, jobWithParallelism(1), kclient, log, false)
+	if !deleted || err != nil {
+		t.Errorf("Expected delete of unprotected job; Got: %v, %v", deleted, err)
+	}
+}
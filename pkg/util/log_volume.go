@@ -0,0 +1,186 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+/*
+Common log volume and logrotate sidecar convention for services writing
+their logs to files:
+
+util.EnsureLogVolume(&podSpec, []string{"nova-api"}, nil)
+util.InjectLogRotateSidecar(&podSpec, logrotateImage, logrotateConf)
+
+Passing an empty image to InjectLogRotateSidecar removes the sidecar,
+RemoveLogVolume removes the volume and all its mounts again.
+*/
+
+const (
+	// LogVolumeName - name of the shared log volume
+	LogVolumeName = "logs"
+	// LogVolumeMountPath - path the log volume gets mounted to
+	LogVolumeMountPath = "/var/log/openstack"
+	// LogRotateContainerName - name of the logrotate sidecar container
+	LogRotateContainerName = "logrotate"
+	// LogRotateConfEnv - env var the logrotate config is passed in to the sidecar
+	LogRotateConfEnv = "LOGROTATE_CONF"
+
+	logRotateInterval = 3600
+)
+
+// EnsureLogVolume - add an emptyDir log volume to the podSpec and mount it
+// into the named containers. Calling it multiple times is safe.
+func EnsureLogVolume(podSpec *corev1.PodSpec, containerNames []string, sizeLimit *resource.Quantity) {
+	volume := corev1.Volume{
+		Name: LogVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				SizeLimit: sizeLimit,
+			},
+		},
+	}
+
+	updated := false
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == LogVolumeName {
+			podSpec.Volumes[i] = volume
+			updated = true
+			break
+		}
+	}
+	if !updated {
+		podSpec.Volumes = append(podSpec.Volumes, volume)
+	}
+
+	for _, name := range containerNames {
+		for i := range podSpec.Containers {
+			if podSpec.Containers[i].Name == name {
+				ensureLogVolumeMount(&podSpec.Containers[i])
+			}
+		}
+	}
+}
+
+// RemoveLogVolume - remove the log volume and all its mounts from the podSpec.
+// Slices without the log volume are left untouched, so a nil slice stays nil
+// and the spec does not differ from the live one.
+func RemoveLogVolume(podSpec *corev1.PodSpec) {
+	if hasLogVolume(podSpec.Volumes) {
+		volumes := []corev1.Volume{}
+		for _, v := range podSpec.Volumes {
+			if v.Name != LogVolumeName {
+				volumes = append(volumes, v)
+			}
+		}
+		podSpec.Volumes = volumes
+	}
+
+	for i := range podSpec.Containers {
+		if !hasLogVolumeMount(podSpec.Containers[i].VolumeMounts) {
+			continue
+		}
+		mounts := []corev1.VolumeMount{}
+		for _, m := range podSpec.Containers[i].VolumeMounts {
+			if m.Name != LogVolumeName {
+				mounts = append(mounts, m)
+			}
+		}
+		podSpec.Containers[i].VolumeMounts = mounts
+	}
+}
+
+func hasLogVolume(volumes []corev1.Volume) bool {
+	for _, v := range volumes {
+		if v.Name == LogVolumeName {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLogVolumeMount(mounts []corev1.VolumeMount) bool {
+	for _, m := range mounts {
+		if m.Name == LogVolumeName {
+			return true
+		}
+	}
+	return false
+}
+
+// InjectLogRotateSidecar - add or update the logrotate sidecar container which
+// rotates the files on the log volume using the passed logrotate config.
+// An empty image removes the sidecar.
+func InjectLogRotateSidecar(podSpec *corev1.PodSpec, image string, conf string) {
+	if image == "" {
+		containers := []corev1.Container{}
+		for _, c := range podSpec.Containers {
+			if c.Name != LogRotateContainerName {
+				containers = append(containers, c)
+			}
+		}
+		podSpec.Containers = containers
+		return
+	}
+
+	sidecar := corev1.Container{
+		Name:    LogRotateContainerName,
+		Image:   image,
+		Command: []string{"/bin/bash", "-c"},
+		Args:    []string{logRotateScript()},
+		Env: []corev1.EnvVar{
+			{
+				Name:  LogRotateConfEnv,
+				Value: conf,
+			},
+		},
+	}
+	ensureLogVolumeMount(&sidecar)
+
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == LogRotateContainerName {
+			podSpec.Containers[i] = sidecar
+			return
+		}
+	}
+	podSpec.Containers = append(podSpec.Containers, sidecar)
+}
+
+func ensureLogVolumeMount(container *corev1.Container) {
+	for _, m := range container.VolumeMounts {
+		if m.Name == LogVolumeName {
+			return
+		}
+	}
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      LogVolumeName,
+		MountPath: LogVolumeMountPath,
+	})
+}
+
+func logRotateScript() string {
+	return fmt.Sprintf(
+		"echo \"$%s\" > /tmp/logrotate.conf; "+
+			"while true; do logrotate -s /tmp/logrotate.status /tmp/logrotate.conf; sleep %d; done",
+		LogRotateConfEnv,
+		logRotateInterval,
+	)
+}
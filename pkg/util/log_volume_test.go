@@ -0,0 +1,88 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestEnsureLogVolume(t *testing.T) {
+	size := resource.MustParse("1Gi")
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "api"}, {Name: "other"}},
+	}
+
+	EnsureLogVolume(&podSpec, []string{"api"}, &size)
+	first := podSpec.DeepCopy()
+	EnsureLogVolume(&podSpec, []string{"api"}, &size)
+
+	if !reflect.DeepEqual(first, &podSpec) {
+		t.Errorf("EnsureLogVolume is not idempotent; Expected: %v; Got: %v", first, podSpec)
+	}
+	if len(podSpec.Volumes) != 1 || *podSpec.Volumes[0].EmptyDir.SizeLimit != size {
+		t.Errorf("Unexpected volumes: %v", podSpec.Volumes)
+	}
+	if len(podSpec.Containers[0].VolumeMounts) != 1 || podSpec.Containers[0].VolumeMounts[0].MountPath != LogVolumeMountPath {
+		t.Errorf("Unexpected volume mounts: %v", podSpec.Containers[0].VolumeMounts)
+	}
+	if len(podSpec.Containers[1].VolumeMounts) != 0 {
+		t.Errorf("Didn't expect volume mounts on container `other`: %v", podSpec.Containers[1].VolumeMounts)
+	}
+
+	RemoveLogVolume(&podSpec)
+	if len(podSpec.Volumes) != 0 || len(podSpec.Containers[0].VolumeMounts) != 0 {
+		t.Errorf("Log volume not removed: %v", podSpec)
+	}
+}
+
+func TestRemoveLogVolumeNoop(t *testing.T) {
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Name: "api"},
+			{Name: "httpd", VolumeMounts: []corev1.VolumeMount{{Name: "config", MountPath: "/etc/httpd"}}},
+		},
+	}
+	expected := podSpec.DeepCopy()
+
+	RemoveLogVolume(&podSpec)
+	if podSpec.Volumes != nil || podSpec.Containers[0].VolumeMounts != nil {
+		t.Errorf("Expected nil slices to stay nil; Got: %v", podSpec)
+	}
+	if !reflect.DeepEqual(&podSpec, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, podSpec)
+	}
+}
+
+func TestInjectLogRotateSidecar(t *testing.T) {
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{{Name: "api"}},
+	}
+
+	InjectLogRotateSidecar(&podSpec, "logrotate:old", "old")
+	InjectLogRotateSidecar(&podSpec, "logrotate:new", "/var/log/openstack/*.log {\n  daily\n}")
+
+	if len(podSpec.Containers) != 2 {
+		t.Fatalf("Expected 2 containers; Got: %v", podSpec.Containers)
+	}
+	sidecar := podSpec.Containers[1]
+	if sidecar.Image != "logrotate:new" {
+		t.Errorf("Expected image `logrotate:new`; Got: %s", sidecar.Image)
+	}
+	expectedArgs := []string{
+		"echo \"$LOGROTATE_CONF\" > /tmp/logrotate.conf; " +
+			"while true; do logrotate -s /tmp/logrotate.status /tmp/logrotate.conf; sleep 3600; done",
+	}
+	if !reflect.DeepEqual(sidecar.Args, expectedArgs) {
+		t.Errorf("Expected args: %v; Got: %v", expectedArgs, sidecar.Args)
+	}
+	if sidecar.Env[0].Value != "/var/log/openstack/*.log {\n  daily\n}" {
+		t.Errorf("Unexpected logrotate config: %s", sidecar.Env[0].Value)
+	}
+
+	InjectLogRotateSidecar(&podSpec, "", "")
+	if len(podSpec.Containers) != 1 || podSpec.Containers[0].Name != "api" {
+		t.Errorf("Sidecar not removed: %v", podSpec.Containers)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Standardized structured logging keys
+const (
+	// LogKeyKind - kind of the object
+	LogKeyKind = "kind"
+	// LogKeyNamespace - namespace of the object
+	LogKeyNamespace = "namespace"
+	// LogKeyName - name of the object
+	LogKeyName = "name"
+	// LogKeyUID - uid of the object
+	LogKeyUID = "uid"
+)
+
+// WithObject - return a logger with the standardized kind, namespace, name
+// and uid keys of the object attached
+func WithObject(log logr.Logger, obj runtime.Object) logr.Logger {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		kind = reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return log.WithValues(LogKeyKind, kind)
+	}
+
+	return log.WithValues(
+		LogKeyKind, kind,
+		LogKeyNamespace, accessor.GetNamespace(),
+		LogKeyName, accessor.GetName(),
+		LogKeyUID, string(accessor.GetUID()),
+	)
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// capturingLogger - logr.Logger recording the key/value pairs attached via WithValues
+type capturingLogger struct {
+	values []interface{}
+}
+
+func (l *capturingLogger) Info(msg string, keysAndValues ...interface{})             {}
+func (l *capturingLogger) Enabled() bool                                             { return true }
+func (l *capturingLogger) Error(err error, msg string, keysAndValues ...interface{}) {}
+func (l *capturingLogger) V(level int) logr.InfoLogger                               { return l }
+func (l *capturingLogger) WithName(name string) logr.Logger                          { return l }
+func (l *capturingLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	l.values = append(l.values, keysAndValues...)
+	return l
+}
+
+func TestWithObject(t *testing.T) {
+	log := &capturingLogger{}
+	WithObject(log, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "test", UID: "1234"},
+	})
+
+	expected := []interface{}{
+		LogKeyKind, "ConfigMap",
+		LogKeyNamespace, "test",
+		LogKeyName, "cm",
+		LogKeyUID, "1234",
+	}
+	if !reflect.DeepEqual(log.values, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, log.values)
+	}
+}
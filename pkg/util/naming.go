@@ -0,0 +1,174 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+Naming conventions for child resources of a CR instance:
+
+util.ServiceName("nova", "internal")   -> nova-internal
+util.JobName("nova", "db-sync")        -> nova-db-sync-job
+util.ConfigMapName("nova", "scripts")  -> nova-scripts
+
+Names longer than 63 characters get shortened with ShortName. When a naming
+scheme changes between operator versions, MigrateName moves the object
+found under the old name to the new one, or deletes it.
+*/
+
+const (
+	// maxNameLength - maximum length of names which are also used as label values or DNS labels
+	maxNameLength = 63
+	// shortNameHashLength - number of hex characters of the hash suffix of shortened names
+	shortNameHashLength = 10
+)
+
+var invalidNameChars = regexp.MustCompile("[^a-z0-9.-]")
+
+// NameFunc - builds the name of a child resource of an instance
+type NameFunc func(instance string, suffix string) string
+
+// ShortName - returns the name unchanged if it fits maxNameLength, otherwise
+// it gets truncated and a hash of the full name appended to keep it unique
+func ShortName(name string) string {
+	if len(name) <= maxNameLength {
+		return name
+	}
+	hash, _ := shortHash(name, shortNameHashLength)
+	return fmt.Sprintf("%s-%s", truncateName(name, maxNameLength-shortNameHashLength-1), hash)
+}
+
+// truncateName - truncate the name to length and trim trailing '-' and '.',
+// so a suffix can be appended without doubling the separator
+func truncateName(name string, length int) string {
+	if len(name) > length {
+		name = name[:length]
+	}
+	return strings.TrimRight(name, "-.")
+}
+
+// SanitizeName - lowercase the name, replace characters which are invalid in
+// a DNS-1123 subdomain with '-' and trim it to 253 characters. Returns an
+// error if the result is still not a valid DNS-1123 subdomain, e.g. empty.
+func SanitizeName(name string) (string, error) {
+	sanitized := invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(sanitized) > validation.DNS1123SubdomainMaxLength {
+		sanitized = sanitized[:validation.DNS1123SubdomainMaxLength]
+	}
+	sanitized = strings.Trim(sanitized, ".-")
+
+	if errs := validation.IsDNS1123Subdomain(sanitized); len(errs) > 0 {
+		return "", fmt.Errorf("Invalid name %q: %s", name, strings.Join(errs, ", "))
+	}
+
+	return sanitized, nil
+}
+
+// ServiceName - name of the Service of an instance for the endpoint, e.g. public
+func ServiceName(instance string, endpoint string) string {
+	return ShortName(fmt.Sprintf("%s-%s", instance, endpoint))
+}
+
+// JobName - name of the Job of an instance for the purpose, e.g. db-sync
+func JobName(instance string, purpose string) string {
+	return ShortName(fmt.Sprintf("%s-%s-job", instance, purpose))
+}
+
+// ConfigMapName - name of the ConfigMap of an instance for the kind, e.g. scripts
+func ConfigMapName(instance string, kind string) string {
+	return ShortName(fmt.Sprintf("%s-%s", instance, kind))
+}
+
+// MigrateName - migrate the object from the name built by oldName to the name
+// built by newName. obj is used to get the object with the old name and
+// determines its type. With rename, a copy is created under the new name
+// unless it already exists, otherwise the operator is expected to create it.
+// In both cases the object with the old name gets deleted.
+// Returns true if an object with the old name was found.
+func MigrateName(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	instance string,
+	suffix string,
+	oldName NameFunc,
+	newName NameFunc,
+	obj runtime.Object,
+	rename bool,
+) (bool, error) {
+	from := oldName(instance, suffix)
+	to := newName(instance, suffix)
+	if from == to {
+		return false, nil
+	}
+
+	err := c.Get(ctx, types.NamespacedName{Name: from, Namespace: namespace}, obj)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if rename {
+		renamed := obj.DeepCopyObject()
+		accessor, err := meta.Accessor(renamed)
+		if err != nil {
+			return true, err
+		}
+		accessor.SetName(to)
+		accessor.SetResourceVersion("")
+		accessor.SetUID("")
+		accessor.SetCreationTimestamp(metav1.Time{})
+		// the cluster IP and node ports are allocated to the old Service
+		if svc, ok := renamed.(*corev1.Service); ok {
+			if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+				svc.Spec.ClusterIP = ""
+			}
+			for i := range svc.Spec.Ports {
+				svc.Spec.Ports[i].NodePort = 0
+			}
+			svc.Spec.HealthCheckNodePort = 0
+		}
+
+		err = c.Create(ctx, renamed)
+		if err != nil && !k8s_errors.IsAlreadyExists(err) {
+			return true, err
+		}
+	}
+
+	err = c.Delete(ctx, obj)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return true, err
+	}
+
+	return true, nil
+}
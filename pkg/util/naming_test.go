@@ -0,0 +1,175 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestShortName(t *testing.T) {
+	long := strings.Repeat("a", 70)
+
+	tests := []struct {
+		name   string
+		length int
+	}{
+		{"nova-internal", len("nova-internal")},
+		{long, maxNameLength},
+	}
+
+	for _, test := range tests {
+		short := ShortName(test.name)
+		if len(short) != test.length {
+			t.Errorf("Expected length: %d; Got: %d (%s)", test.length, len(short), short)
+		}
+	}
+	names := map[string]string{}
+	for i := 0; i < 1000; i++ {
+		name := fmt.Sprintf("%s-%d", long, i)
+		short := ShortName(name)
+		if other, ok := names[short]; ok {
+			t.Fatalf("Expected different short names; Got: %s for %s and %s", short, name, other)
+		}
+		names[short] = name
+	}
+
+	// the truncation point falls on a '-', it must not end up doubled
+	short := ShortName(strings.Repeat("a", 51) + "-" + strings.Repeat("b", 20))
+	if strings.Contains(short, "--") || !strings.HasPrefix(short, strings.Repeat("a", 51)+"-") {
+		t.Errorf("Unexpected short name: %s", short)
+	}
+	if JobName("nova", "db-sync") != "nova-db-sync-job" {
+		t.Errorf("Expected: nova-db-sync-job; Got: %s", JobName("nova", "db-sync"))
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		valid    bool
+	}{
+		{"nova-api", "nova-api", true},
+		{"Nova_API.internal", "nova-api.internal", true},
+		{"-nova api!", "nova-api", true},
+		{strings.Repeat("a", 300), strings.Repeat("a", 253), true},
+		{"a..b", "", false},
+		{"___", "", false},
+	}
+
+	for _, test := range tests {
+		sanitized, err := SanitizeName(test.name)
+		if test.valid && err != nil {
+			t.Errorf("Unexpected error for %q: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Didn't get expected error for %q", test.name)
+		}
+		if sanitized != test.expected {
+			t.Errorf("Expected: %s; Got: %s", test.expected, sanitized)
+		}
+	}
+}
+
+func legacyName(instance string, suffix string) string {
+	return fmt.Sprintf("%s-%s-legacy", instance, suffix)
+}
+
+func TestMigrateNameRename(t *testing.T) {
+	c := fake.NewFakeClient(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "nova-scripts-legacy", Namespace: "test", ResourceVersion: "1"},
+			Data:       map[string]string{"init.sh": "true"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "nova-public-legacy", Namespace: "test", ResourceVersion: "1"},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.1"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "nova-external-legacy", Namespace: "test", ResourceVersion: "1"},
+			Spec: corev1.ServiceSpec{
+				Type:                  corev1.ServiceTypeLoadBalancer,
+				ClusterIP:             "10.0.0.2",
+				Ports:                 []corev1.ServicePort{{Name: "api", Port: 8774, NodePort: 30774}},
+				HealthCheckNodePort:   30775,
+				ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyTypeLocal,
+			},
+		},
+	)
+
+	found, err := MigrateName(context.TODO(), c, "test", "nova", "scripts", legacyName, ConfigMapName, &corev1.ConfigMap{}, true)
+	if err != nil || !found {
+		t.Fatalf("Expected configmap to be migrated; Got: %v, %v", found, err)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "nova-scripts", Namespace: "test"}, cm); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cm.Data["init.sh"] != "true" {
+		t.Errorf("Expected data to be copied; Got: %v", cm.Data)
+	}
+	err = c.Get(context.TODO(), types.NamespacedName{Name: "nova-scripts-legacy", Namespace: "test"}, &corev1.ConfigMap{})
+	if !k8s_errors.IsNotFound(err) {
+		t.Errorf("Expected old configmap to be deleted; Got: %v", err)
+	}
+
+	found, err = MigrateName(context.TODO(), c, "test", "nova", "public", legacyName, ServiceName, &corev1.Service{}, true)
+	if err != nil || !found {
+		t.Fatalf("Expected service to be migrated; Got: %v, %v", found, err)
+	}
+	svc := &corev1.Service{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "nova-public", Namespace: "test"}, svc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if svc.Spec.ClusterIP != "" {
+		t.Errorf("Expected cluster IP to be cleared; Got: %s", svc.Spec.ClusterIP)
+	}
+	err = c.Get(context.TODO(), types.NamespacedName{Name: "nova-public-legacy", Namespace: "test"}, &corev1.Service{})
+	if !k8s_errors.IsNotFound(err) {
+		t.Errorf("Expected old service to be deleted; Got: %v", err)
+	}
+
+	found, err = MigrateName(context.TODO(), c, "test", "nova", "external", legacyName, ServiceName, &corev1.Service{}, true)
+	if err != nil || !found {
+		t.Fatalf("Expected node port service to be migrated; Got: %v, %v", found, err)
+	}
+	svc = &corev1.Service{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "nova-external", Namespace: "test"}, svc); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if svc.Spec.Ports[0].NodePort != 0 || svc.Spec.HealthCheckNodePort != 0 {
+		t.Errorf("Expected node ports to be cleared; Got: %v", svc.Spec)
+	}
+	if svc.Spec.Ports[0].Port != 8774 {
+		t.Errorf("Expected port 8774 to be kept; Got: %v", svc.Spec.Ports)
+	}
+
+	found, err = MigrateName(context.TODO(), c, "test", "nova", "public", legacyName, ServiceName, &corev1.Service{}, true)
+	if err != nil || found {
+		t.Errorf("Expected nothing left to migrate; Got: %v, %v", found, err)
+	}
+}
+
+func TestMigrateNameDelete(t *testing.T) {
+	c := fake.NewFakeClient(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "nova-public-legacy", Namespace: "test"},
+	})
+
+	found, err := MigrateName(context.TODO(), c, "test", "nova", "public", legacyName, ServiceName, &corev1.Service{}, false)
+	if err != nil || !found {
+		t.Fatalf("Expected service to be found; Got: %v, %v", found, err)
+	}
+	for _, name := range []string{"nova-public-legacy", "nova-public"} {
+		err = c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "test"}, &corev1.Service{})
+		if !k8s_errors.IsNotFound(err) {
+			t.Errorf("Expected service %s to not exist; Got: %v", name, err)
+		}
+	}
+}
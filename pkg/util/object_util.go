@@ -1,13 +1,33 @@
 package util
 
 import (
+	"context"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// AnnotationPrefix - prefix of the annotations set by lib-common
+	AnnotationPrefix = "openstack.org"
+	// DeletionProtectionAnnotation - set to "true" to protect the objects owned by a CR from deletion
+	DeletionProtectionAnnotation = AnnotationPrefix + "/deletion-protection"
+)
+
+// ErrDeletionProtected - returned by delete helpers when the owner is deletion protected
+var ErrDeletionProtected = errors.New("deletion protected")
+
 // ObjectHash creates a deep object hash and return it as a safe encoded string
 func ObjectHash(i interface{}) (string, error) {
 	// Convert the hashSource to a byte slice so that it can be hashed
@@ -18,3 +38,268 @@ func ObjectHash(i interface{}) (string, error) {
 	hash := sha256.Sum256(hashBytes)
 	return rand.SafeEncodeString(fmt.Sprint(hash)), nil
 }
+
+// shortHash - returns the first length hex characters of the sha256 of the
+// JSON encoded object. Used for name suffixes, where the encoding of
+// ObjectHash would leave little entropy in a short prefix.
+func shortHash(i interface{}, length int) (string, error) {
+	hashBytes, err := json.Marshal(i)
+	if err != nil {
+		return "", fmt.Errorf("unable to convert to JSON: %v", err)
+	}
+	hash := sha256.Sum256(hashBytes)
+	return hex.EncodeToString(hash[:])[:length], nil
+}
+
+// HashSource - named input to ComputeInputHash
+type HashSource struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Data      map[string][]byte
+}
+
+// ConfigMapHashSource - HashSource from the data of a ConfigMap
+func ConfigMapHashSource(cm *corev1.ConfigMap) HashSource {
+	data := map[string][]byte{}
+	for k, v := range cm.Data {
+		data[k] = []byte(v)
+	}
+	for k, v := range cm.BinaryData {
+		data[k] = v
+	}
+	return HashSource{Kind: "ConfigMap", Namespace: cm.Namespace, Name: cm.Name, Data: data}
+}
+
+// SecretHashSource - HashSource from the data of a Secret
+func SecretHashSource(secret *corev1.Secret) HashSource {
+	return HashSource{Kind: "Secret", Namespace: secret.Namespace, Name: secret.Name, Data: secret.Data}
+}
+
+// BytesHashSource - HashSource from raw bytes
+func BytesHashSource(name string, b []byte) HashSource {
+	return HashSource{Kind: "Bytes", Name: name, Data: map[string][]byte{"": b}}
+}
+
+// ComputeInputHash - compute a combined hash over multiple sources. The
+// sources get sorted by kind, namespace and name, so the result does not
+// depend on the order the sources get passed in.
+func ComputeInputHash(sources ...HashSource) (string, error) {
+	sorted := make([]HashSource, len(sources))
+	copy(sorted, sources)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Kind != sorted[j].Kind {
+			return sorted[i].Kind < sorted[j].Kind
+		}
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	// maps get marshalled with sorted keys, so the hash is stable
+	return ObjectHash(sorted)
+}
+
+// EnsureLabels - add the labels to the object and patch it if any of them
+// was missing or had a different value. Returns true if the object got patched.
+func EnsureLabels(ctx context.Context, c client.Client, obj runtime.Object, labels map[string]string) (bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	return ensureMetadataMap(ctx, c, obj, labels, accessor.GetLabels, accessor.SetLabels)
+}
+
+// EnsureAnnotations - add the annotations to the object and patch it if any of
+// them was missing or had a different value. Returns true if the object got patched.
+func EnsureAnnotations(ctx context.Context, c client.Client, obj runtime.Object, annotations map[string]string) (bool, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	return ensureMetadataMap(ctx, c, obj, annotations, accessor.GetAnnotations, accessor.SetAnnotations)
+}
+
+func ensureMetadataMap(
+	ctx context.Context,
+	c client.Client,
+	obj runtime.Object,
+	values map[string]string,
+	get func() map[string]string,
+	set func(map[string]string),
+) (bool, error) {
+	current := get()
+
+	changed := false
+	for k, v := range values {
+		if cur, ok := current[k]; !ok || cur != v {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(obj.DeepCopyObject())
+	updated := map[string]string{}
+	for k, v := range current {
+		updated[k] = v
+	}
+	for k, v := range values {
+		updated[k] = v
+	}
+	set(updated)
+
+	if err := c.Patch(ctx, obj, patch); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetControllerOwnerRef - returns the owner reference of the controller of the object, if any
+func GetControllerOwnerRef(obj metav1.Object) (*metav1.OwnerReference, bool) {
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		return nil, false
+	}
+	return ref, true
+}
+
+// HashAnnotationKey - returns the annotation key to store the hash with the name in
+func HashAnnotationKey(name string) string {
+	return fmt.Sprintf("%s/%s-hash", AnnotationPrefix, name)
+}
+
+// CheckDeletionProtection - returns an error wrapping ErrDeletionProtected if
+// the object is deletion protected and force is not set
+func CheckDeletionProtection(obj metav1.Object, force bool) error {
+	if !force && IsDeletionProtected(obj) {
+		return fmt.Errorf("%s %s: %w", obj.GetNamespace(), obj.GetName(), ErrDeletionProtected)
+	}
+	return nil
+}
+
+// IsDeletionProtected - returns true if the object carries the deletion protection annotation
+func IsDeletionProtected(obj metav1.Object) bool {
+	return obj.GetAnnotations()[DeletionProtectionAnnotation] == "true"
+}
+
+// MergeOwnerReferences - add the owner references to the existing ones,
+// deduplicated by UID. When the same owner is in both lists, the existing
+// reference is kept and marked as controller if either of them is. Returns
+// an error if the result would have more than one controller reference, as
+// the API server rejects such objects.
+func MergeOwnerReferences(existing []metav1.OwnerReference, add []metav1.OwnerReference) ([]metav1.OwnerReference, error) {
+	merged := []metav1.OwnerReference{}
+	index := map[string]int{}
+	controllerIndex := -1
+
+	for _, ref := range append(append([]metav1.OwnerReference{}, existing...), add...) {
+		i, ok := index[string(ref.UID)]
+		if !ok {
+			i = len(merged)
+			index[string(ref.UID)] = i
+			merged = append(merged, *ref.DeepCopy())
+			merged[i].Controller = nil
+			if ref.Controller != nil {
+				notController := false
+				merged[i].Controller = &notController
+			}
+		}
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+
+		if controllerIndex != -1 && controllerIndex != i {
+			return nil, fmt.Errorf("Owner %s %s can not be controller, %s %s already is",
+				ref.Kind, ref.Name, merged[controllerIndex].Kind, merged[controllerIndex].Name)
+		}
+		controller := true
+		merged[i].Controller = &controller
+		controllerIndex = i
+	}
+
+	return merged, nil
+}
+
+// ApplyJSONPatch - apply the RFC 6902 JSON patch to the object
+func ApplyJSONPatch(ctx context.Context, c client.Client, obj runtime.Object, patch []byte) error {
+	return c.Patch(ctx, obj, client.RawPatch(types.JSONPatchType, patch))
+}
+
+// NeedsUpdate - returns true if the desired object differs from the live one,
+// comparing a hash of the normalized spec, labels and annotations. Status,
+// the type meta and all other metadata are ignored. Fields defaulted by the
+// API server count as difference, so desired should be the live object with
+// the changes applied, like a mutate function of CreateOrUpdate does.
+func NeedsUpdate(live runtime.Object, desired runtime.Object) (bool, error) {
+	liveHash, err := normalizedObjectHash(live)
+	if err != nil {
+		return false, err
+	}
+	desiredHash, err := normalizedObjectHash(desired)
+	if err != nil {
+		return false, err
+	}
+	return liveHash != desiredHash, nil
+}
+
+func normalizedObjectHash(obj runtime.Object) (string, error) {
+	fields, err := normalizedObjectFields(obj)
+	if err != nil {
+		return "", err
+	}
+	pruneEmptyValues(fields)
+	return ObjectHash(fields)
+}
+
+// pruneEmptyValues - remove nil values and empty maps and lists, so e.g. nil
+// and empty labels hash the same. Other zero values, like 0 replicas or a
+// false paused, are kept as they are explicit settings.
+func pruneEmptyValues(m map[string]interface{}) {
+	for k, v := range m {
+		if isEmptyValue(v) {
+			delete(m, k)
+		}
+	}
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		pruneEmptyValues(value)
+		return len(value) == 0
+	case []interface{}:
+		for _, item := range value {
+			if m, ok := item.(map[string]interface{}); ok {
+				pruneEmptyValues(m)
+			}
+		}
+		return len(value) == 0
+	}
+	return false
+}
+
+func normalizedObjectFields(obj runtime.Object) (map[string]interface{}, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(u, "status")
+	delete(u, "apiVersion")
+	delete(u, "kind")
+	if metadata, ok := u["metadata"].(map[string]interface{}); ok {
+		u["metadata"] = map[string]interface{}{
+			"labels":      metadata["labels"],
+			"annotations": metadata["annotations"],
+		}
+	}
+
+	return u, nil
+}
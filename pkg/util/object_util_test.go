@@ -0,0 +1,318 @@
+package util
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestComputeInputHash(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "config"},
+		Data:       map[string]string{"a.conf": "a", "b.conf": "b"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "config"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+
+	hash1, err := ComputeInputHash(ConfigMapHashSource(cm), SecretHashSource(secret), BytesHashSource("env", []byte("x")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hash2, _ := ComputeInputHash(BytesHashSource("env", []byte("x")), SecretHashSource(secret), ConfigMapHashSource(cm))
+	if hash1 != hash2 {
+		t.Errorf("Expected hash to not depend on source order; Got: %s and %s", hash1, hash2)
+	}
+
+	secret.Data["password"] = []byte("changed")
+	hash3, _ := ComputeInputHash(ConfigMapHashSource(cm), SecretHashSource(secret), BytesHashSource("env", []byte("x")))
+	if hash1 == hash3 {
+		t.Errorf("Expected hash to change when a source changes")
+	}
+
+	// same name in different namespaces, the data moving between them must change the hash
+	a := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "a"},
+		Data:       map[string][]byte{"password": []byte("one")},
+	}
+	b := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "b"},
+		Data:       map[string][]byte{"password": []byte("two")},
+	}
+	hash4, _ := ComputeInputHash(SecretHashSource(a), SecretHashSource(b))
+	hash5, _ := ComputeInputHash(SecretHashSource(b), SecretHashSource(a))
+	if hash4 != hash5 {
+		t.Errorf("Expected hash to not depend on source order; Got: %s and %s", hash4, hash5)
+	}
+	a.Data, b.Data = b.Data, a.Data
+	hash6, _ := ComputeInputHash(SecretHashSource(a), SecretHashSource(b))
+	if hash4 == hash6 {
+		t.Errorf("Expected hash to change when the data moves between namespaces")
+	}
+}
+
+func TestEnsureLabelsAndAnnotations(t *testing.T) {
+	c := fake.NewFakeClient(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cm",
+			Namespace: "test",
+			Labels:    map[string]string{"existing": "label"},
+		},
+	})
+	key := types.NamespacedName{Name: "cm", Namespace: "test"}
+
+	for _, test := range []struct {
+		values             map[string]string
+		labelsChanged      bool
+		annotationsChanged bool
+	}{
+		{map[string]string{"new": "value"}, true, true},
+		{map[string]string{"new": "value"}, false, false},
+		{map[string]string{"existing": "label"}, false, true},
+	} {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(context.TODO(), key, cm); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		changed, err := EnsureLabels(context.TODO(), c, cm, test.values)
+		if err != nil || changed != test.labelsChanged {
+			t.Errorf("EnsureLabels %v; Expected changed: %v; Got: %v, %v", test.values, test.labelsChanged, changed, err)
+		}
+
+		if err := c.Get(context.TODO(), key, cm); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		changed, err = EnsureAnnotations(context.TODO(), c, cm, test.values)
+		if err != nil || changed != test.annotationsChanged {
+			t.Errorf("EnsureAnnotations %v; Expected changed: %v; Got: %v, %v", test.values, test.annotationsChanged, changed, err)
+		}
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(context.TODO(), key, cm); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"existing": "label", "new": "value"}
+	if !reflect.DeepEqual(cm.Labels, expected) || !reflect.DeepEqual(cm.Annotations, expected) {
+		t.Errorf("Expected labels and annotations: %v; Got: %v, %v", expected, cm.Labels, cm.Annotations)
+	}
+}
+
+func TestGetControllerOwnerRef(t *testing.T) {
+	controller := true
+	owner := metav1.OwnerReference{APIVersion: "v1", Kind: "Owner", Name: "owner", UID: "1", Controller: &controller}
+	other := metav1.OwnerReference{APIVersion: "v1", Kind: "Other", Name: "other", UID: "2"}
+
+	obj := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{other, owner}}}
+	ref, ok := GetControllerOwnerRef(obj)
+	if !ok || !reflect.DeepEqual(*ref, owner) {
+		t.Errorf("Expected controller owner ref %v; Got: %v, %v", owner, ref, ok)
+	}
+
+	obj = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{other}}}
+	if ref, ok := GetControllerOwnerRef(obj); ok || ref != nil {
+		t.Errorf("Didn't expect a controller owner ref; Got: %v", ref)
+	}
+}
+
+func TestHashAnnotationKey(t *testing.T) {
+	for name, expected := range map[string]string{
+		"certs":  "openstack.org/certs-hash",
+		"config": "openstack.org/config-hash",
+	} {
+		if key := HashAnnotationKey(name); key != expected {
+			t.Errorf("Expected: %s; Got: %s", expected, key)
+		}
+	}
+	if HashAnnotationKey("certs") != CertHashAnnotation {
+		t.Errorf("Expected %s to follow the hash annotation key format", CertHashAnnotation)
+	}
+}
+
+func TestMergeOwnerReferences(t *testing.T) {
+	controller := true
+	notController := false
+	a := metav1.OwnerReference{Kind: "A", Name: "a", UID: "1"}
+	aController := metav1.OwnerReference{Kind: "A", Name: "a", UID: "1", Controller: &controller}
+	b := metav1.OwnerReference{Kind: "B", Name: "b", UID: "2", Controller: &notController}
+	c := metav1.OwnerReference{Kind: "C", Name: "c", UID: "3"}
+
+	tests := []struct {
+		existing []metav1.OwnerReference
+		add      []metav1.OwnerReference
+		expected []metav1.OwnerReference
+	}{
+		// dedup
+		{[]metav1.OwnerReference{a, b}, []metav1.OwnerReference{b, c, c}, []metav1.OwnerReference{a, b, c}},
+		// controller flag of the added reference is preserved
+		{[]metav1.OwnerReference{a}, []metav1.OwnerReference{aController}, []metav1.OwnerReference{aController}},
+		// controller flag of the existing reference is preserved
+		{[]metav1.OwnerReference{aController}, []metav1.OwnerReference{a}, []metav1.OwnerReference{aController}},
+		{nil, nil, []metav1.OwnerReference{}},
+	}
+
+	for _, test := range tests {
+		merged, err := MergeOwnerReferences(test.existing, test.add)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(merged, test.expected) {
+			t.Errorf("Expected: %v; Got: %v", test.expected, merged)
+		}
+	}
+	if a.Controller != nil {
+		t.Errorf("Input owner reference got modified: %v", a)
+	}
+
+	// a second controller is refused, whether added or promoted
+	bController := metav1.OwnerReference{Kind: "B", Name: "b", UID: "2", Controller: &controller}
+	for _, test := range []struct {
+		existing []metav1.OwnerReference
+		add      []metav1.OwnerReference
+	}{
+		{[]metav1.OwnerReference{aController}, []metav1.OwnerReference{bController}},
+		{[]metav1.OwnerReference{aController, b}, []metav1.OwnerReference{bController}},
+	} {
+		if _, err := MergeOwnerReferences(test.existing, test.add); err == nil {
+			t.Errorf("Didn't get expected error for second controller in %v + %v", test.existing, test.add)
+		}
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	c := fake.NewFakeClient(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "test"},
+		Data:       map[string]string{"a": "1"},
+	})
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "test"}}
+	patch := []byte(`[{"op": "replace", "path": "/data/a", "value": "2"}, {"op": "add", "path": "/data/b", "value": "3"}]`)
+	if err := ApplyJSONPatch(context.TODO(), c, cm, patch); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"a": "2", "b": "3"}
+	if !reflect.DeepEqual(cm.Data, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, cm.Data)
+	}
+
+	if err := ApplyJSONPatch(context.TODO(), c, cm, []byte(`[{"op": "test", "path": "/data/a", "value": "1"}]`)); err == nil {
+		t.Errorf("Didn't get expected error for failing test operation")
+	}
+}
+
+func TestNeedsUpdate(t *testing.T) {
+	live := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "svc", Namespace: "test", ResourceVersion: "42", UID: "uid"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:       "10.0.0.1",
+			Type:            corev1.ServiceTypeClusterIP,
+			SessionAffinity: corev1.ServiceAffinityNone,
+			Ports: []corev1.ServicePort{{
+				Name:       "api",
+				Port:       8774,
+				Protocol:   corev1.ProtocolTCP,
+				TargetPort: intstr.FromInt(8774),
+			}},
+		},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "1.2.3.4"}}}},
+	}
+
+	// only metadata noise and status differ
+	identical := live.DeepCopy()
+	identical.ResourceVersion = "43"
+	identical.Generation = 2
+	identical.Labels = map[string]string{}
+	identical.Status = corev1.ServiceStatus{}
+	differentPort := live.DeepCopy()
+	differentPort.Spec.Ports[0].Port = 8775
+	differentLabels := live.DeepCopy()
+	differentLabels.Labels = map[string]string{"app": "nova"}
+	differentType := live.DeepCopy()
+	differentType.Spec.Type = corev1.ServiceTypeLoadBalancer
+	removedPort := live.DeepCopy()
+	removedPort.Spec.Ports = nil
+
+	tests := []struct {
+		desired  *corev1.Service
+		expected bool
+	}{
+		{identical, false},
+		{differentPort, true},
+		{differentLabels, true},
+		{differentType, true},
+		{removedPort, true},
+	}
+
+	for _, test := range tests {
+		needsUpdate, err := NeedsUpdate(live, test.desired)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if needsUpdate != test.expected {
+			t.Errorf("Expected: %v; Got: %v for %v", test.expected, needsUpdate, test.desired)
+		}
+	}
+}
+
+func TestNeedsUpdateZeroValuesAndLists(t *testing.T) {
+	replicas := int32(3)
+	live := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "test"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Paused:   true,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "api",
+							Env:  []corev1.EnvVar{{Name: "DEBUG", Value: "true"}, {Name: "WORKERS", Value: "4"}},
+						},
+						{Name: "httpd"},
+					},
+				},
+			},
+		},
+	}
+
+	scaledToZero := live.DeepCopy()
+	zero := int32(0)
+	scaledToZero.Spec.Replicas = &zero
+	unpaused := live.DeepCopy()
+	unpaused.Spec.Paused = false
+	removedEnv := live.DeepCopy()
+	removedEnv.Spec.Template.Spec.Containers[0].Env = removedEnv.Spec.Template.Spec.Containers[0].Env[:1]
+	removedContainer := live.DeepCopy()
+	removedContainer.Spec.Template.Spec.Containers = removedContainer.Spec.Template.Spec.Containers[:1]
+
+	tests := []struct {
+		name     string
+		desired  *appsv1.Deployment
+		expected bool
+	}{
+		{"unchanged", live.DeepCopy(), false},
+		{"scale to zero", scaledToZero, true},
+		{"unpause", unpaused, true},
+		{"removed env var", removedEnv, true},
+		{"removed container", removedContainer, true},
+	}
+
+	for _, test := range tests {
+		needsUpdate, err := NeedsUpdate(live, test.desired)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if needsUpdate != test.expected {
+			t.Errorf("%s; Expected: %v; Got: %v", test.name, test.expected, needsUpdate)
+		}
+	}
+}
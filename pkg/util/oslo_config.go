@@ -0,0 +1,171 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+Build oslo.config INI files from structured data instead of templating:
+
+cfg := util.OsloConfig{}
+cfg.Set("database", "connection", dbURL)
+cfg.Set("DEFAULT", "enabled_apis", "osapi_compute", "metadata")
+merged, conflicts := util.MergeOsloConfig(cfg, userCfg)
+data, err := merged.Render()
+
+'$' gets escaped as '$$' so oslo.config does not interpolate it, and the
+first line of a value is quoted when oslo.config would otherwise alter it,
+e.g. a password with leading whitespace or starting with a quote. Multi line
+values are rendered as continuation lines. oslo.config strips the whitespace
+around continuation lines and ends a value at a blank line, values which can
+not be represented that way are rejected with an error.
+*/
+
+// OsloConfig - oslo.config sections, mapping section -> key -> values.
+// Keys with multiple values get rendered as repeated keys (MultiStrOpt).
+type OsloConfig map[string]map[string][]string
+
+// Set - set the value(s) of the key in the section
+func (c OsloConfig) Set(section string, key string, values ...string) {
+	if _, ok := c[section]; !ok {
+		c[section] = map[string][]string{}
+	}
+	c[section][key] = values
+}
+
+// Render - render all sections with DEFAULT first, the other sections and
+// all keys in alphabetical order
+func (c OsloConfig) Render() (string, error) {
+	sections := []string{}
+	for name := range c {
+		if name != "DEFAULT" {
+			sections = append(sections, name)
+		}
+	}
+	sort.Strings(sections)
+	if _, ok := c["DEFAULT"]; ok {
+		sections = append([]string{"DEFAULT"}, sections...)
+	}
+
+	rendered := []string{}
+	for _, name := range sections {
+		section, err := renderOsloSection(name, c[name])
+		if err != nil {
+			return "", err
+		}
+		rendered = append(rendered, section)
+	}
+	return strings.Join(rendered, "\n"), nil
+}
+
+// OsloSection - render a single section from single valued keys
+func OsloSection(name string, kv map[string]string) (string, error) {
+	values := map[string][]string{}
+	for k, v := range kv {
+		values[k] = []string{v}
+	}
+	return renderOsloSection(name, values)
+}
+
+// MergeOsloConfig - merge the user config over the operator config on key
+// level. Returns the merged config and the section/key of all operator
+// values which got overridden with a different value.
+func MergeOsloConfig(operator OsloConfig, user OsloConfig) (OsloConfig, []string) {
+	merged := OsloConfig{}
+	for section, keys := range operator {
+		for key, values := range keys {
+			merged.Set(section, key, values...)
+		}
+	}
+
+	conflicts := []string{}
+	for section, keys := range user {
+		for key, values := range keys {
+			if current, ok := merged[section][key]; ok && !equalStrings(current, values) {
+				conflicts = append(conflicts, fmt.Sprintf("%s/%s", section, key))
+			}
+			merged.Set(section, key, values...)
+		}
+	}
+	sort.Strings(conflicts)
+
+	return merged, conflicts
+}
+
+func renderOsloSection(name string, kv map[string][]string) (string, error) {
+	keys := []string{}
+	for k := range kv {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s]\n", name)
+	for _, k := range keys {
+		for _, v := range kv[k] {
+			quoted, err := quoteOsloValue(v)
+			if err != nil {
+				return "", fmt.Errorf("Invalid value of %s/%s: %v", name, k, err)
+			}
+			fmt.Fprintf(&b, "%s = %s\n", k, quoted)
+		}
+	}
+	return b.String(), nil
+}
+
+// quoteOsloValue - quote/escape a value so oslo.config parses it unchanged.
+// oslo.config interpolates '$', strips surrounding whitespace and one level of
+// matching quotes of the first line, joins indented continuation lines with a
+// newline after stripping their whitespace and ends a value at a blank line.
+func quoteOsloValue(v string) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(v, "$", "$$"), "\n")
+	for i, line := range lines {
+		if i == 0 {
+			if line != strings.TrimSpace(line) ||
+				strings.HasPrefix(line, "\"") || strings.HasPrefix(line, "'") ||
+				strings.ContainsAny(line, "#;") {
+				lines[i] = "\"" + line + "\""
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			return "", fmt.Errorf("blank line %d would end the value", i)
+		}
+		if line != strings.TrimSpace(line) {
+			return "", fmt.Errorf("whitespace around line %d would be stripped", i)
+		}
+		lines[i] = "    " + line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOsloSection(t *testing.T) {
+	tests := []struct {
+		kv       map[string]string
+		expected string
+	}{
+		{
+			map[string]string{"b": "2", "a": "1"},
+			"[test]\na = 1\nb = 2\n",
+		},
+		{
+			map[string]string{"password": "pa#ss;word"},
+			"[test]\npassword = \"pa#ss;word\"\n",
+		},
+		{
+			map[string]string{"password": " leading space"},
+			"[test]\npassword = \" leading space\"\n",
+		},
+		{
+			map[string]string{"password": "\"quoted\""},
+			"[test]\npassword = \"\"quoted\"\"\n",
+		},
+		{
+			map[string]string{"multi": "line1\nline2"},
+			"[test]\nmulti = line1\n    line2\n",
+		},
+		{
+			map[string]string{"password": "pa$$word"},
+			"[test]\npassword = pa$$$$word\n",
+		},
+		{
+			map[string]string{"password": "$foo"},
+			"[test]\npassword = $$foo\n",
+		},
+		{
+			map[string]string{"multi": " first\n\"second\"\n#third"},
+			"[test]\nmulti = \" first\"\n    \"second\"\n    #third\n",
+		},
+	}
+
+	for _, test := range tests {
+		s, err := OsloSection("test", test.kv)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if s != test.expected {
+			t.Errorf("Expected: %q; Got: %q", test.expected, s)
+		}
+	}
+}
+
+func TestOsloSectionInvalid(t *testing.T) {
+	for _, v := range []string{"line1\n\nline3", "line1\n  \nline3", "line1\n  indented"} {
+		if _, err := OsloSection("test", map[string]string{"multi": v}); err == nil {
+			t.Errorf("Didn't get expected error for %q", v)
+		}
+	}
+}
+
+func TestOsloConfigRender(t *testing.T) {
+	cfg := OsloConfig{}
+	cfg.Set("database", "connection", "mysql://db")
+	cfg.Set("DEFAULT", "enabled_apis", "osapi_compute", "metadata")
+
+	expected := "[DEFAULT]\nenabled_apis = osapi_compute\nenabled_apis = metadata\n" +
+		"\n[database]\nconnection = mysql://db\n"
+	s, err := cfg.Render()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s != expected {
+		t.Errorf("Expected: %q; Got: %q", expected, s)
+	}
+}
+
+func TestMergeOsloConfig(t *testing.T) {
+	operator := OsloConfig{}
+	operator.Set("DEFAULT", "debug", "false")
+	operator.Set("database", "connection", "mysql://db")
+
+	user := OsloConfig{}
+	user.Set("DEFAULT", "debug", "true")
+	user.Set("database", "connection", "mysql://db")
+	user.Set("oslo_messaging_notifications", "driver", "noop")
+
+	merged, conflicts := MergeOsloConfig(operator, user)
+
+	expected := OsloConfig{
+		"DEFAULT":                      {"debug": {"true"}},
+		"database":                     {"connection": {"mysql://db"}},
+		"oslo_messaging_notifications": {"driver": {"noop"}},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, merged)
+	}
+	if !reflect.DeepEqual(conflicts, []string{"DEFAULT/debug"}) {
+		t.Errorf("Expected conflicts: [DEFAULT/debug]; Got: %v", conflicts)
+	}
+	if operator["DEFAULT"]["debug"][0] != "false" {
+		t.Errorf("Operator config got modified: %v", operator)
+	}
+}
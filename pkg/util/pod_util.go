@@ -0,0 +1,374 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// QuarantinedLabelsAnnotation - annotation recording the labels of a quarantined pod
+	QuarantinedLabelsAnnotation = AnnotationPrefix + "/quarantined-labels"
+)
+
+// HasImagePullSecret - returns true if the pod references the image pull secret
+func HasImagePullSecret(pod corev1.Pod, secretName string) bool {
+	for _, s := range pod.Spec.ImagePullSecrets {
+		if s.Name == secretName {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePullSecrets - returns the image pull secrets referenced by the pod
+// which do not exist in the namespace
+func ValidatePullSecrets(ctx context.Context, c client.Client, namespace string, pod corev1.Pod) ([]string, error) {
+	missing := []string{}
+
+	for _, s := range pod.Spec.ImagePullSecrets {
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Name: s.Name, Namespace: namespace}, secret)
+		if err != nil {
+			if k8s_errors.IsNotFound(err) {
+				missing = append(missing, s.Name)
+				continue
+			}
+			return nil, err
+		}
+	}
+
+	return missing, nil
+}
+
+// QuarantinePod - replace the labels of the pod with the quarantine label, so
+// it is no longer selected by its StatefulSet/Deployment or Service, without
+// deleting it. The original labels get recorded in an annotation so they can
+// be restored using UnquarantinePod.
+func QuarantinePod(ctx context.Context, c client.Client, namespace string, name string, quarantineLabelKey string) error {
+	pod := &corev1.Pod{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, pod)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := pod.Annotations[QuarantinedLabelsAnnotation]; ok {
+		return nil
+	}
+
+	labels, err := json.Marshal(pod.Labels)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[QuarantinedLabelsAnnotation] = string(labels)
+	pod.Labels = map[string]string{quarantineLabelKey: "true"}
+
+	return c.Patch(ctx, pod, patch)
+}
+
+// UnquarantinePod - restore the labels recorded by QuarantinePod
+func UnquarantinePod(ctx context.Context, c client.Client, namespace string, name string) error {
+	pod := &corev1.Pod{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, pod)
+	if err != nil {
+		return err
+	}
+
+	recorded, ok := pod.Annotations[QuarantinedLabelsAnnotation]
+	if !ok {
+		return nil
+	}
+
+	labels := map[string]string{}
+	if err := json.Unmarshal([]byte(recorded), &labels); err != nil {
+		return fmt.Errorf("Invalid %s annotation on pod %s: %v", QuarantinedLabelsAnnotation, name, err)
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	pod.Labels = labels
+	delete(pod.Annotations, QuarantinedLabelsAnnotation)
+
+	return c.Patch(ctx, pod, patch)
+}
+
+// GetPodListWithLabel - get all pods in the namespace matching the labels
+func GetPodListWithLabel(ctx context.Context, c client.Client, namespace string, labels map[string]string) (*corev1.PodList, error) {
+	pods := &corev1.PodList{}
+	err := c.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels(labels))
+	if err != nil {
+		return nil, err
+	}
+	return pods, nil
+}
+
+// WaitForPodsDeleted - check if all pods matching the labels are gone.
+// Returns a result requeueing after requeueAfter plus jitter while matching
+// pods remain, so the reconcile does not block while waiting. since is the
+// time the wait started, e.g. recorded in the status when the pods got
+// deleted. Once timeout passed since then, an error wrapping
+// wait.ErrWaitTimeout is returned.
+func WaitForPodsDeleted(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	labels map[string]string,
+	since time.Time,
+	timeout time.Duration,
+	requeueAfter time.Duration,
+) (reconcile.Result, error) {
+	pods, err := GetPodListWithLabel(ctx, c, namespace, labels)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if len(pods.Items) > 0 {
+		res, err := requeueUntil(since.Add(timeout), requeueAfter)
+		if err != nil {
+			return res, fmt.Errorf("%d pods with labels %v still exist after %s: %w", len(pods.Items), labels, timeout, err)
+		}
+		return res, nil
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// requeueUntil - result requeueing after requeueAfter plus jitter, but not
+// past the deadline. Returns wait.ErrWaitTimeout once the deadline passed.
+func requeueUntil(deadline time.Time, requeueAfter time.Duration) (reconcile.Result, error) {
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return reconcile.Result{}, wait.ErrWaitTimeout
+	}
+
+	delay := jitterInterval(requeueAfter, DefaultPollJitter)
+	if delay > remaining {
+		delay = remaining
+	}
+	return reconcile.Result{RequeueAfter: delay}, nil
+}
+
+// WaitForReadyQuorum - check if at least quorum pods matching the labels are
+// ready. Returns false and a result requeueing after requeueAfter plus jitter
+// while the quorum is not reached, so the reconcile does not block while
+// waiting. since is the time the wait started, once timeout passed since
+// then without reaching the quorum an error wrapping wait.ErrWaitTimeout is
+// returned.
+func WaitForReadyQuorum(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	labels map[string]string,
+	quorum int,
+	since time.Time,
+	timeout time.Duration,
+	requeueAfter time.Duration,
+) (bool, reconcile.Result, error) {
+	pods, err := GetPodListWithLabel(ctx, c, namespace, labels)
+	if err != nil {
+		return false, reconcile.Result{}, err
+	}
+
+	ready := 0
+	for _, pod := range pods.Items {
+		if isPodReady(pod) {
+			ready++
+		}
+	}
+	if ready < quorum {
+		res, err := requeueUntil(since.Add(timeout), requeueAfter)
+		if err != nil {
+			return false, res, fmt.Errorf("%d of %d required pods with labels %v ready after %s: %w", ready, quorum, labels, timeout, err)
+		}
+		return false, res, nil
+	}
+
+	return true, reconcile.Result{}, nil
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// PodLogStreamer - opens the log stream of a pod
+type PodLogStreamer interface {
+	StreamLogs(ctx context.Context, namespace string, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error)
+}
+
+// ClientsetLogStreamer - PodLogStreamer using the pods log subresource.
+// kclient required as the controller-runtime client can not stream logs
+type ClientsetLogStreamer struct {
+	kclient kubernetes.Interface
+}
+
+// NewClientsetLogStreamer - returns a PodLogStreamer using the clientset
+func NewClientsetLogStreamer(kclient kubernetes.Interface) *ClientsetLogStreamer {
+	return &ClientsetLogStreamer{kclient: kclient}
+}
+
+// StreamLogs - open the log stream of the pod
+func (s *ClientsetLogStreamer) StreamLogs(
+	ctx context.Context,
+	namespace string,
+	name string,
+	opts *corev1.PodLogOptions,
+) (io.ReadCloser, error) {
+	return s.kclient.CoreV1().Pods(namespace).GetLogs(name, opts).Stream(ctx)
+}
+
+// GetLogs - get the last tailLines log lines of the container of all running
+// pods matching the labels, keyed by pod name. Pods without the container
+// are skipped. With an empty container name the pods' only container is used.
+// A pod whose logs can not be read does not abort the collection, the logs
+// of the other pods get returned together with an aggregated error.
+func GetLogs(
+	ctx context.Context,
+	c client.Client,
+	streamer PodLogStreamer,
+	namespace string,
+	podLabels map[string]string,
+	container string,
+	tailLines int64,
+) (map[string]string, error) {
+	pods, err := GetPodListWithLabel(ctx, c, namespace, podLabels)
+	if err != nil {
+		return nil, err
+	}
+
+	logs := map[string]string{}
+	errs := []error{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || !hasContainer(pod, container) {
+			continue
+		}
+
+		b, err := readPodLogs(ctx, streamer, namespace, pod.Name, &corev1.PodLogOptions{
+			Container: container,
+			TailLines: &tailLines,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Failed to get logs of pod %s: %v", pod.Name, err))
+			continue
+		}
+		logs[pod.Name] = string(b)
+	}
+
+	return logs, utilerrors.NewAggregate(errs)
+}
+
+func readPodLogs(
+	ctx context.Context,
+	streamer PodLogStreamer,
+	namespace string,
+	name string,
+	opts *corev1.PodLogOptions,
+) ([]byte, error) {
+	stream, err := streamer.StreamLogs(ctx, namespace, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	return ioutil.ReadAll(stream)
+}
+
+func hasContainer(pod corev1.Pod, container string) bool {
+	if container == "" {
+		return true
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPodsOnUnschedulableNodes - returns the names of the pods matching the
+// labels which run on cordoned, i.e. unschedulable, nodes
+func GetPodsOnUnschedulableNodes(ctx context.Context, c client.Client, namespace string, labels map[string]string) ([]string, error) {
+	pods, err := GetPodListWithLabel(ctx, c, namespace, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	unschedulable := map[string]bool{}
+	names := []string{}
+	for _, pod := range pods.Items {
+		nodeName := pod.Spec.NodeName
+		if nodeName == "" {
+			continue
+		}
+
+		cordoned, ok := unschedulable[nodeName]
+		if !ok {
+			node := &corev1.Node{}
+			err := c.Get(ctx, types.NamespacedName{Name: nodeName}, node)
+			if err != nil && !k8s_errors.IsNotFound(err) {
+				return nil, err
+			}
+			cordoned = err == nil && node.Spec.Unschedulable
+			unschedulable[nodeName] = cordoned
+		}
+
+		if cordoned {
+			names = append(names, pod.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// GetStuckTerminatingPods - returns the names of the pods which are still
+// terminating after their grace period passed. On a graceful delete the API
+// server sets the deletionTimestamp to the time of the request plus the grace
+// period, so a pod is stuck once its deletionTimestamp is in the past.
+func GetStuckTerminatingPods(podList corev1.PodList) []string {
+	now := time.Now()
+	names := []string{}
+	for _, pod := range podList.Items {
+		if pod.DeletionTimestamp != nil && now.After(pod.DeletionTimestamp.Time) {
+			names = append(names, pod.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,316 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func podWithPullSecrets(names ...string) corev1.Pod {
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "test"},
+	}
+	for _, n := range names {
+		pod.Spec.ImagePullSecrets = append(pod.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: n})
+	}
+	return pod
+}
+
+func TestHasImagePullSecret(t *testing.T) {
+	pod := podWithPullSecrets("one", "two")
+
+	if !HasImagePullSecret(pod, "two") {
+		t.Errorf("Expected pull secret `two` to be present")
+	}
+	if HasImagePullSecret(pod, "three") {
+		t.Errorf("Didn't expect pull secret `three` to be present")
+	}
+}
+
+func TestValidatePullSecrets(t *testing.T) {
+	c := fake.NewFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "present", Namespace: "test"},
+	})
+
+	tests := []struct {
+		pod     corev1.Pod
+		missing []string
+	}{
+		{podWithPullSecrets(), []string{}},
+		{podWithPullSecrets("present"), []string{}},
+		{podWithPullSecrets("present", "missing"), []string{"missing"}},
+	}
+
+	for _, test := range tests {
+		missing, err := ValidatePullSecrets(context.TODO(), c, "test", test.pod)
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(missing, test.missing) {
+			t.Errorf("Expected: %v; Got: %v", test.missing, missing)
+		}
+	}
+}
+
+func TestQuarantinePod(t *testing.T) {
+	labels := map[string]string{"app": "api", "component": "api"}
+	c := fake.NewFakeClient(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "test", Labels: labels},
+	})
+	key := types.NamespacedName{Name: "pod", Namespace: "test"}
+
+	if err := QuarantinePod(context.TODO(), c, "test", "pod", "quarantine"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pod := &corev1.Pod{}
+	if err := c.Get(context.TODO(), key, pod); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"quarantine": "true"}
+	if !reflect.DeepEqual(pod.Labels, expected) {
+		t.Errorf("Expected labels: %v; Got: %v", expected, pod.Labels)
+	}
+
+	if err := UnquarantinePod(context.TODO(), c, "test", "pod"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	pod = &corev1.Pod{}
+	if err := c.Get(context.TODO(), key, pod); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(pod.Labels, labels) {
+		t.Errorf("Expected restored labels: %v; Got: %v", labels, pod.Labels)
+	}
+	if _, ok := pod.Annotations[QuarantinedLabelsAnnotation]; ok {
+		t.Errorf("Expected %s annotation to be removed", QuarantinedLabelsAnnotation)
+	}
+}
+
+func TestWaitForPodsDeleted(t *testing.T) {
+	labels := map[string]string{"app": "api"}
+	c := fake.NewFakeClient(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-0", Namespace: "test", Labels: labels}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test"}},
+	)
+	since := time.Now()
+
+	res, err := WaitForPodsDeleted(context.TODO(), c, "test", labels, since, time.Minute, time.Second)
+	if err != nil || res.RequeueAfter < time.Second || res.RequeueAfter > time.Second+time.Second/5 {
+		t.Errorf("Expected jittered requeue while pods remain; Got: %v, %v", res, err)
+	}
+
+	// the requeue does not overshoot the deadline
+	res, err = WaitForPodsDeleted(context.TODO(), c, "test", labels, since.Add(-time.Minute+time.Second), time.Minute, time.Hour)
+	if err != nil || res.RequeueAfter <= 0 || res.RequeueAfter > time.Second {
+		t.Errorf("Expected requeue until the deadline; Got: %v, %v", res, err)
+	}
+
+	res, err = WaitForPodsDeleted(context.TODO(), c, "test", labels, since.Add(-2*time.Minute), time.Minute, time.Second)
+	if !errors.Is(err, wait.ErrWaitTimeout) || res.RequeueAfter != 0 {
+		t.Errorf("Expected timeout error while pods remain past the deadline; Got: %v, %v", res, err)
+	}
+
+	if err := c.Delete(context.TODO(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api-0", Namespace: "test"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	res, err = WaitForPodsDeleted(context.TODO(), c, "test", labels, since.Add(-2*time.Minute), time.Minute, time.Second)
+	if err != nil || res.RequeueAfter != 0 {
+		t.Errorf("Expected no requeue once pods are gone; Got: %v, %v", res, err)
+	}
+}
+
+func TestWaitForReadyQuorum(t *testing.T) {
+	labels := map[string]string{"app": "galera"}
+	pod := func(name string, ready corev1.ConditionStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: labels},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+			},
+		}
+	}
+	c := fake.NewFakeClient(
+		pod("galera-0", corev1.ConditionTrue),
+		pod("galera-1", corev1.ConditionTrue),
+		pod("galera-2", corev1.ConditionFalse),
+	)
+
+	since := time.Now()
+
+	tests := []struct {
+		quorum   int
+		since    time.Time
+		expected bool
+		timeout  bool
+	}{
+		{2, since, true, false},
+		{3, since, false, false},
+		{2, since.Add(-2 * time.Minute), true, false},
+		{3, since.Add(-2 * time.Minute), false, true},
+	}
+
+	for _, test := range tests {
+		reached, res, err := WaitForReadyQuorum(context.TODO(), c, "test", labels, test.quorum, test.since, time.Minute, time.Second)
+		if test.timeout != errors.Is(err, wait.ErrWaitTimeout) || (!test.timeout && err != nil) {
+			t.Errorf("Quorum %d since %s; Expected timeout: %v; Got: %v", test.quorum, test.since, test.timeout, err)
+		}
+		requeue := res.RequeueAfter >= time.Second
+		if reached != test.expected || requeue != (!test.expected && !test.timeout) {
+			t.Errorf("Quorum %d; Expected: %v; Got: %v, %v", test.quorum, test.expected, reached, res)
+		}
+	}
+}
+
+type fakeLogStreamer struct {
+	failing map[string]bool
+}
+
+func (s *fakeLogStreamer) StreamLogs(ctx context.Context, namespace string, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+	if s.failing[name] {
+		return nil, fmt.Errorf("container not started")
+	}
+	return ioutil.NopCloser(strings.NewReader(fmt.Sprintf("%s/%s tail %d", name, opts.Container, *opts.TailLines))), nil
+}
+
+func TestGetLogs(t *testing.T) {
+	labels := map[string]string{"app": "api"}
+	runningPod := func(name string, container string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: labels},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: container}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	pending := runningPod("api-2", "api")
+	pending.Status.Phase = corev1.PodPending
+
+	c := fake.NewFakeClient(
+		runningPod("api-0", "api"),
+		runningPod("api-1", "other"),
+		pending,
+		runningPod("api-3", "api"),
+		runningPod("api-4", "api"),
+	)
+
+	logs, err := GetLogs(context.TODO(), c, &fakeLogStreamer{}, "test", labels, "api", 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"api-0": "api-0/api tail 10",
+		"api-3": "api-3/api tail 10",
+		"api-4": "api-4/api tail 10",
+	}
+	if !reflect.DeepEqual(logs, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, logs)
+	}
+
+	// a failing pod does not abort collecting the logs of the others
+	logs, err = GetLogs(context.TODO(), c, &fakeLogStreamer{failing: map[string]bool{"api-3": true}}, "test", labels, "api", 10)
+	if err == nil || !strings.Contains(err.Error(), "api-3") {
+		t.Errorf("Expected error for pod api-3; Got: %v", err)
+	}
+	delete(expected, "api-3")
+	if !reflect.DeepEqual(logs, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, logs)
+	}
+}
+
+func TestClientsetLogStreamer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/test/pods/api-0/log" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "%s tail %s", r.URL.Query().Get("container"), r.URL.Query().Get("tailLines"))
+	}))
+	defer server.Close()
+
+	kclient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tailLines := int64(10)
+	stream, err := NewClientsetLogStreamer(kclient).StreamLogs(context.TODO(), "test", "api-0", &corev1.PodLogOptions{
+		Container: "api",
+		TailLines: &tailLines,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer stream.Close()
+
+	b, err := ioutil.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(b) != "api tail 10" {
+		t.Errorf("Expected: api tail 10; Got: %s", b)
+	}
+}
+
+func TestGetPodsOnUnschedulableNodes(t *testing.T) {
+	labels := map[string]string{"app": "api"}
+	podOnNode := func(name string, node string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Labels: labels},
+			Spec:       corev1.PodSpec{NodeName: node},
+		}
+	}
+	c := fake.NewFakeClient(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cordoned"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "schedulable"}},
+		podOnNode("api-0", "cordoned"),
+		podOnNode("api-1", "schedulable"),
+		podOnNode("api-2", "cordoned"),
+		podOnNode("api-3", ""),
+	)
+
+	names, err := GetPodsOnUnschedulableNodes(context.TODO(), c, "test", labels)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"api-0", "api-2"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, names)
+	}
+}
+
+func TestGetStuckTerminatingPods(t *testing.T) {
+	deletedAt := func(name string, d time.Duration) corev1.Pod {
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if d != 0 {
+			ts := metav1.NewTime(time.Now().Add(d))
+			pod.DeletionTimestamp = &ts
+		}
+		return pod
+	}
+	podList := corev1.PodList{Items: []corev1.Pod{
+		deletedAt("running", 0),
+		deletedAt("terminating", 30*time.Second),
+		deletedAt("stuck", -time.Minute),
+	}}
+
+	names := GetStuckTerminatingPods(podList)
+	expected := []string{"stuck"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, names)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// DefaultPollJitter - fraction of the interval added as random jitter, so
+// the polls and requeues of many CRs reconciled at the same time spread out
+const DefaultPollJitter = 0.2
+
+// PollWithJitter - check the condition immediately and then every interval
+// plus up to jitter * interval random delay until it returns true or an
+// error, the timeout expires or the context is done, whatever comes first.
+// Returns the total time waited and wait.ErrWaitTimeout on timeout, or the
+// context error when the context is done, e.g. the reconcile deadline passed.
+func PollWithJitter(
+	ctx context.Context,
+	interval time.Duration,
+	jitter float64,
+	timeout time.Duration,
+	condition wait.ConditionFunc,
+) (time.Duration, error) {
+	start := time.Now()
+	deadline := start.Add(timeout)
+
+	for {
+		done, err := condition()
+		if err != nil {
+			return time.Since(start), err
+		}
+		if done {
+			return time.Since(start), nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return time.Since(start), wait.ErrWaitTimeout
+		}
+		delay := jitterInterval(interval, jitter)
+		if delay > remaining {
+			delay = remaining
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Since(start), ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// jitterInterval - interval plus a random delay of up to jitter * interval
+func jitterInterval(interval time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	return wait.Jitter(interval, jitter)
+}
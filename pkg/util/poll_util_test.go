@@ -0,0 +1,56 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestJitterInterval(t *testing.T) {
+	interval := 100 * time.Millisecond
+
+	for i := 0; i < 100; i++ {
+		d := jitterInterval(interval, 0.5)
+		if d < interval || d > interval+interval/2 {
+			t.Fatalf("Expected interval between %v and %v; Got: %v", interval, interval+interval/2, d)
+		}
+	}
+	if d := jitterInterval(interval, 0); d != interval {
+		t.Errorf("Expected: %v; Got: %v", interval, d)
+	}
+}
+
+func TestPollWithJitter(t *testing.T) {
+	calls := 0
+	_, err := PollWithJitter(context.TODO(), time.Millisecond, DefaultPollJitter, time.Second, func() (bool, error) {
+		calls++
+		return calls == 3, nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected condition to be met on third call; Got: %d calls, %v", calls, err)
+	}
+
+	waited, err := PollWithJitter(context.TODO(), time.Millisecond, DefaultPollJitter, 10*time.Millisecond, func() (bool, error) {
+		return false, nil
+	})
+	if err != wait.ErrWaitTimeout || waited < 10*time.Millisecond {
+		t.Errorf("Expected timeout after 10ms; Got: %v, %v", waited, err)
+	}
+}
+
+func TestPollWithJitterContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	waited, err := PollWithJitter(ctx, time.Millisecond, DefaultPollJitter, time.Minute, func() (bool, error) {
+		return false, nil
+	})
+	if err != context.Canceled {
+		t.Errorf("Expected: %v; Got: %v", context.Canceled, err)
+	}
+	if waited > time.Second {
+		t.Errorf("Expected early exit on cancellation; Waited: %v", waited)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// CRFailedError - returned when a CR another CR depends on reports a terminal failure
+type CRFailedError struct {
+	Kind          string
+	Name          types.NamespacedName
+	ConditionType string
+	Reason        string
+	Message       string
+}
+
+func (e *CRFailedError) Error() string {
+	return fmt.Sprintf("%s %s condition %s failed: %s %s", e.Kind, e.Name, e.ConditionType, e.Reason, e.Message)
+}
+
+// WaitForCRReadyCondition - read the CR as unstructured and check the condition
+// in .status.conditions, which can either be a metav1.Condition list or a
+// lib-common condition list carrying a severity. Returns a result requeueing
+// after requeueAfter while the CR is missing or the condition is not True,
+// and a *CRFailedError when the condition reports a terminal failure, i.e.
+// it is False with severity Error or reason Failed/Error.
+func WaitForCRReadyCondition(
+	ctx context.Context,
+	c client.Client,
+	gvk schema.GroupVersionKind,
+	name types.NamespacedName,
+	conditionType string,
+	requeueAfter time.Duration,
+) (reconcile.Result, error) {
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(gvk)
+	err := c.Get(ctx, name, cr)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	conditions, _, err := unstructured.NestedSlice(cr.Object, "status", "conditions")
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	for _, cond := range conditions {
+		condition, ok := cond.(map[string]interface{})
+		if !ok || condition["type"] != conditionType {
+			continue
+		}
+
+		status, _ := condition["status"].(string)
+		if status == "True" {
+			return reconcile.Result{}, nil
+		}
+
+		reason, _ := condition["reason"].(string)
+		severity, _ := condition["severity"].(string)
+		if status == "False" && (severity == "Error" || reason == "Failed" || reason == "Error") {
+			message, _ := condition["message"].(string)
+			return reconcile.Result{}, &CRFailedError{
+				Kind:          gvk.Kind,
+				Name:          name,
+				ConditionType: conditionType,
+				Reason:        reason,
+				Message:       message,
+			}
+		}
+		break
+	}
+
+	return reconcile.Result{RequeueAfter: requeueAfter}, nil
+}
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var keystoneGVK = schema.GroupVersionKind{Group: "keystone.openstack.org", Version: "v1beta1", Kind: "KeystoneAPI"}
+
+func keystoneAPI(name string, conditions ...interface{}) *unstructured.Unstructured {
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(keystoneGVK)
+	cr.SetName(name)
+	cr.SetNamespace("test")
+	_ = unstructured.SetNestedSlice(cr.Object, conditions, "status", "conditions")
+	return cr
+}
+
+func TestWaitForCRReadyCondition(t *testing.T) {
+	c := fake.NewFakeClient(
+		// metav1.Condition format
+		keystoneAPI("ready", map[string]interface{}{"type": "Ready", "status": "True", "reason": "Ready"}),
+		keystoneAPI("progressing", map[string]interface{}{"type": "Ready", "status": "False", "reason": "Progressing"}),
+		keystoneAPI("failed", map[string]interface{}{"type": "Ready", "status": "False", "reason": "Failed"}),
+		// lib-common condition format
+		keystoneAPI("error", map[string]interface{}{"type": "Ready", "status": "False", "severity": "Error", "reason": "DBSync"}),
+		keystoneAPI("warning", map[string]interface{}{"type": "Ready", "status": "False", "severity": "Warning", "reason": "DBSync"}),
+	)
+
+	tests := []struct {
+		name    string
+		requeue bool
+		failed  bool
+	}{
+		{"ready", false, false},
+		{"progressing", true, false},
+		{"failed", false, true},
+		{"error", false, true},
+		{"warning", true, false},
+		{"missing", true, false},
+	}
+
+	for _, test := range tests {
+		res, err := WaitForCRReadyCondition(context.TODO(), c, keystoneGVK,
+			types.NamespacedName{Name: test.name, Namespace: "test"}, "Ready", time.Second)
+
+		_, failed := err.(*CRFailedError)
+		if failed != test.failed || (!failed && err != nil) {
+			t.Errorf("%s: Expected failed: %v; Got: %v", test.name, test.failed, err)
+		}
+		if (res.RequeueAfter > 0) != test.requeue {
+			t.Errorf("%s: Expected requeue: %v; Got: %v", test.name, test.requeue, res)
+		}
+	}
+}
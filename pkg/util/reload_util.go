@@ -0,0 +1,222 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+/*
+Containers which can reload their config on a signal instead of being
+restarted get marked on the pod template:
+
+util.MarkContainerReloadable(&template.ObjectMeta, "api")
+
+On a config change the controller decides between reload and restart:
+
+decision := util.DecideReload(template, changedHashes, map[string][]string{"config": {"api"}})
+switch decision.Action {
+case util.ActionReload:
+	executor := util.NewSPDYExecutor(restConfig, kclient)
+	// util.SignalContainer(ctx, executor, ...) for each pod and decision.Containers
+case util.ActionRestart:
+	// util.RestartOnHashChange
+}
+
+The pods need shareProcessNamespace disabled so the service runs as PID 1
+of its container.
+*/
+
+const (
+	// ReloadableContainersAnnotation - pod template annotation listing the
+	// containers which reload their config on a signal
+	ReloadableContainersAnnotation = AnnotationPrefix + "/reloadable-containers"
+	// DefaultReloadSignal - signal sent to reload the config
+	DefaultReloadSignal = "HUP"
+)
+
+// ReloadAction - action required to apply changed config to running pods
+type ReloadAction string
+
+const (
+	// ActionNone - nothing changed
+	ActionNone ReloadAction = "None"
+	// ActionReload - signal the containers to reload their config
+	ActionReload ReloadAction = "Reload"
+	// ActionRestart - restart the pods
+	ActionRestart ReloadAction = "Restart"
+)
+
+// ReloadDecision - result of DecideReload
+type ReloadDecision struct {
+	Action ReloadAction
+	// Containers to signal on ActionReload
+	Containers []string
+}
+
+// ContainerExecutor - runs a command in a container
+type ContainerExecutor interface {
+	Exec(ctx context.Context, namespace string, podName string, opts *corev1.PodExecOptions) error
+}
+
+// SPDYExecutor - ContainerExecutor using the remotecommand SPDY executor of client-go
+type SPDYExecutor struct {
+	config  *rest.Config
+	kclient kubernetes.Interface
+}
+
+// NewSPDYExecutor - returns a ContainerExecutor running the commands via the
+// pods exec subresource. kclient required as the controller-runtime client
+// can not exec into pods
+func NewSPDYExecutor(config *rest.Config, kclient kubernetes.Interface) *SPDYExecutor {
+	return &SPDYExecutor{
+		config:  config,
+		kclient: kclient,
+	}
+}
+
+// Exec - run the command of opts in the pod. The stream of this client-go
+// version can not be cancelled, so ctx is not honored once the command runs.
+func (e *SPDYExecutor) Exec(ctx context.Context, namespace string, podName string, opts *corev1.PodExecOptions) error {
+	req := e.kclient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(opts, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(e.config, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = executor.Stream(remotecommand.StreamOptions{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return err
+	}
+	return nil
+}
+
+// MarkContainerReloadable - add the container to the reloadable containers annotation
+func MarkContainerReloadable(meta *metav1.ObjectMeta, container string) {
+	containers := reloadableContainers(*meta)
+	for _, c := range containers {
+		if c == container {
+			return
+		}
+	}
+	containers = append(containers, container)
+	sort.Strings(containers)
+
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[ReloadableContainersAnnotation] = strings.Join(containers, ",")
+}
+
+// IsContainerReloadable - returns true if the container is listed in the reloadable containers annotation
+func IsContainerReloadable(meta metav1.ObjectMeta, container string) bool {
+	for _, c := range reloadableContainers(meta) {
+		if c == container {
+			return true
+		}
+	}
+	return false
+}
+
+func reloadableContainers(meta metav1.ObjectMeta) []string {
+	value := meta.Annotations[ReloadableContainersAnnotation]
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// DecideReload - decide how to apply the changed hashes to the pods of the
+// template. consumers maps a hash name to the containers using the related
+// config. A reload is sufficient if every container consuming a changed hash
+// is marked reloadable on the template, any other change requires a restart.
+func DecideReload(template corev1.PodTemplateSpec, changedHashes []string, consumers map[string][]string) ReloadDecision {
+	if len(changedHashes) == 0 {
+		return ReloadDecision{Action: ActionNone}
+	}
+
+	seen := map[string]bool{}
+	containers := []string{}
+	for _, hash := range changedHashes {
+		consuming, ok := consumers[hash]
+		if !ok || len(consuming) == 0 {
+			return ReloadDecision{Action: ActionRestart}
+		}
+		for _, c := range consuming {
+			if !IsContainerReloadable(template.ObjectMeta, c) {
+				return ReloadDecision{Action: ActionRestart}
+			}
+			if !seen[c] {
+				seen[c] = true
+				containers = append(containers, c)
+			}
+		}
+	}
+	sort.Strings(containers)
+
+	return ReloadDecision{Action: ActionReload, Containers: containers}
+}
+
+// SignalExecOptions - exec options sending the signal to PID 1 of the container
+func SignalExecOptions(container string, signal string) *corev1.PodExecOptions {
+	return &corev1.PodExecOptions{
+		Container: container,
+		Command:   []string{"kill", "-" + strings.TrimPrefix(signal, "SIG"), "1"},
+		Stdout:    true,
+		Stderr:    true,
+	}
+}
+
+// SignalContainer - send the signal, e.g. HUP, to PID 1 of the container of the pod
+func SignalContainer(
+	ctx context.Context,
+	executor ContainerExecutor,
+	namespace string,
+	podName string,
+	container string,
+	signal string,
+) error {
+	err := executor.Exec(ctx, namespace, podName, SignalExecOptions(container, signal))
+	if err != nil {
+		return fmt.Errorf("Failed to send signal %s to container %s of pod %s: %v", signal, container, podName, err)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+type recordingExecutor struct {
+	opts []*corev1.PodExecOptions
+}
+
+func (e *recordingExecutor) Exec(ctx context.Context, namespace string, podName string, opts *corev1.PodExecOptions) error {
+	e.opts = append(e.opts, opts)
+	return nil
+}
+
+func TestMarkContainerReloadable(t *testing.T) {
+	meta := metav1.ObjectMeta{}
+	MarkContainerReloadable(&meta, "api")
+	MarkContainerReloadable(&meta, "httpd")
+	MarkContainerReloadable(&meta, "api")
+
+	if meta.Annotations[ReloadableContainersAnnotation] != "api,httpd" {
+		t.Errorf("Expected: api,httpd; Got: %s", meta.Annotations[ReloadableContainersAnnotation])
+	}
+	if !IsContainerReloadable(meta, "httpd") || IsContainerReloadable(meta, "logrotate") {
+		t.Errorf("Unexpected reloadable containers: %v", meta.Annotations)
+	}
+}
+
+func TestDecideReload(t *testing.T) {
+	template := corev1.PodTemplateSpec{}
+	MarkContainerReloadable(&template.ObjectMeta, "api")
+	MarkContainerReloadable(&template.ObjectMeta, "httpd")
+
+	consumers := map[string][]string{
+		"config": {"api", "httpd"},
+		"policy": {"api"},
+		"logs":   {"api", "logrotate"},
+	}
+
+	tests := []struct {
+		changed  []string
+		expected ReloadDecision
+	}{
+		{[]string{}, ReloadDecision{Action: ActionNone}},
+		{[]string{"policy"}, ReloadDecision{Action: ActionReload, Containers: []string{"api"}}},
+		{[]string{"policy", "config"}, ReloadDecision{Action: ActionReload, Containers: []string{"api", "httpd"}}},
+		{[]string{"config", "certs"}, ReloadDecision{Action: ActionRestart}},
+		// logrotate consumes the logs config but is not marked reloadable
+		{[]string{"logs"}, ReloadDecision{Action: ActionRestart}},
+	}
+
+	for _, test := range tests {
+		decision := DecideReload(template, test.changed, consumers)
+		if !reflect.DeepEqual(decision, test.expected) {
+			t.Errorf("Changed %v; Expected: %v; Got: %v", test.changed, test.expected, decision)
+		}
+	}
+
+	// nothing marked on the template, every change requires a restart
+	decision := DecideReload(corev1.PodTemplateSpec{}, []string{"policy"}, consumers)
+	if decision.Action != ActionRestart {
+		t.Errorf("Expected: %v; Got: %v", ActionRestart, decision.Action)
+	}
+}
+
+func TestSignalContainer(t *testing.T) {
+	tests := []struct {
+		signal   string
+		expected []string
+	}{
+		{"HUP", []string{"kill", "-HUP", "1"}},
+		{"SIGUSR1", []string{"kill", "-USR1", "1"}},
+	}
+
+	for _, test := range tests {
+		executor := &recordingExecutor{}
+		if err := SignalContainer(context.TODO(), executor, "test", "pod", "api", test.signal); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		opts := executor.opts[0]
+		if opts.Container != "api" || !reflect.DeepEqual(opts.Command, test.expected) {
+			t.Errorf("Expected: %v in api; Got: %v in %s", test.expected, opts.Command, opts.Container)
+		}
+	}
+}
+
+func TestSPDYExecutorRequest(t *testing.T) {
+	var requested *url.URL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL
+		http.Error(w, "upgrade refused", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	config := &rest.Config{Host: server.URL}
+	kclient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = SignalContainer(context.TODO(), NewSPDYExecutor(config, kclient), "test", "pod", "api", "HUP")
+	if err == nil {
+		t.Fatalf("Expected an error as the server refuses the upgrade")
+	}
+
+	if requested == nil {
+		t.Fatalf("Expected a request to the exec subresource")
+	}
+	if requested.Path != "/api/v1/namespaces/test/pods/pod/exec" {
+		t.Errorf("Expected: /api/v1/namespaces/test/pods/pod/exec; Got: %s", requested.Path)
+	}
+	query := requested.Query()
+	if query.Get("container") != "api" || !reflect.DeepEqual(query["command"], []string{"kill", "-HUP", "1"}) {
+		t.Errorf("Expected: kill -HUP 1 in api; Got: %v", query)
+	}
+}
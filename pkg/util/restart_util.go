@@ -0,0 +1,122 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CertHashAnnotation - pod template annotation carrying the hash of the mounted certs
+var CertHashAnnotation = HashAnnotationKey("certs")
+
+const (
+	// RestartedAtAnnotation - pod template annotation also used by kubectl rollout restart
+	RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	// RestartReasonAnnotation - pod template annotation carrying the reason of the last restart
+	RestartReasonAnnotation = AnnotationPrefix + "/restart-reason"
+)
+
+// RollRestartForCertChange - set the cert hash annotation on the pod template
+// of the StatefulSet. When the hash changed, the StatefulSet controller rolls
+// the pods one by one in the order defined by its update strategy.
+// Returns true if the StatefulSet got patched.
+func RollRestartForCertChange(ctx context.Context, c client.Client, sts *appsv1.StatefulSet, certHash string) (bool, error) {
+	if sts.Spec.Template.Annotations[CertHashAnnotation] == certHash {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(sts.DeepCopy())
+	if sts.Spec.Template.Annotations == nil {
+		sts.Spec.Template.Annotations = map[string]string{}
+	}
+	sts.Spec.Template.Annotations[CertHashAnnotation] = certHash
+
+	if err := c.Patch(ctx, sts, patch); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// RolloutRestart - restart the pods of a Deployment, StatefulSet or DaemonSet
+// the same way `kubectl rollout restart` does by setting the restartedAt
+// annotation on the pod template, together with the reason of the restart.
+func RolloutRestart(ctx context.Context, c client.Client, workload runtime.Object, reason string) error {
+	patch := client.MergeFrom(workload.DeepCopyObject())
+
+	template, err := getPodTemplate(workload)
+	if err != nil {
+		return err
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+	template.Annotations[RestartReasonAnnotation] = reason
+
+	return c.Patch(ctx, workload, patch)
+}
+
+// RestartOnHashChange - compare the hash recorded in the pod template of the
+// workload with the new hash and restart the workload if they differ.
+// Returns true if the workload got restarted.
+func RestartOnHashChange(ctx context.Context, c client.Client, workload runtime.Object, hashName string, newHash string) (bool, error) {
+	template, err := getPodTemplate(workload)
+	if err != nil {
+		return false, err
+	}
+
+	key := HashAnnotationKey(hashName)
+	if template.Annotations[key] == newHash {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject())
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[key] = newHash
+	template.Annotations[RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+	template.Annotations[RestartReasonAnnotation] = fmt.Sprintf("%s changed", hashName)
+
+	if err := c.Patch(ctx, workload, patch); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// getPodTemplate - return the pod template of a workload object
+func getPodTemplate(workload runtime.Object) (*corev1.PodTemplateSpec, error) {
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		return &w.Spec.Template, nil
+	case *appsv1.StatefulSet:
+		return &w.Spec.Template, nil
+	case *appsv1.DaemonSet:
+		return &w.Spec.Template, nil
+	default:
+		return nil, fmt.Errorf("Unsupported workload type %T", workload)
+	}
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testStatefulSet() *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "sts", Namespace: "test"},
+	}
+}
+
+func TestRollRestartForCertChange(t *testing.T) {
+	c := fake.NewFakeClient(testStatefulSet())
+	key := types.NamespacedName{Name: "sts", Namespace: "test"}
+
+	for _, test := range []struct {
+		hash    string
+		changed bool
+	}{
+		{"hash1", true},
+		{"hash1", false},
+		{"hash2", true},
+	} {
+		sts := &appsv1.StatefulSet{}
+		if err := c.Get(context.TODO(), key, sts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		changed, err := RollRestartForCertChange(context.TODO(), c, sts, test.hash)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if changed != test.changed {
+			t.Errorf("Hash %s; Expected changed: %v; Got: %v", test.hash, test.changed, changed)
+		}
+
+		if err := c.Get(context.TODO(), key, sts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if sts.Spec.Template.Annotations[CertHashAnnotation] != test.hash {
+			t.Errorf("Expected pod template annotation %s; Got: %v", test.hash, sts.Spec.Template.Annotations)
+		}
+	}
+}
+
+func TestRestartOnHashChange(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployment", Namespace: "test"},
+	}
+	c := fake.NewFakeClient(deployment, testStatefulSet())
+
+	for _, workload := range []runtime.Object{deployment, &appsv1.StatefulSet{}} {
+		key := types.NamespacedName{Name: "deployment", Namespace: "test"}
+		if _, ok := workload.(*appsv1.StatefulSet); ok {
+			key.Name = "sts"
+		}
+
+		for _, test := range []struct {
+			hash    string
+			changed bool
+		}{
+			{"hash1", true},
+			{"hash1", false},
+			{"hash2", true},
+		} {
+			if err := c.Get(context.TODO(), key, workload); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			changed, err := RestartOnHashChange(context.TODO(), c, workload, "config", test.hash)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if changed != test.changed {
+				t.Errorf("%T hash %s; Expected changed: %v; Got: %v", workload, test.hash, test.changed, changed)
+			}
+
+			if err := c.Get(context.TODO(), key, workload); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			template, _ := getPodTemplate(workload)
+			if template.Annotations[HashAnnotationKey("config")] != test.hash ||
+				template.Annotations[RestartedAtAnnotation] == "" ||
+				template.Annotations[RestartReasonAnnotation] != "config changed" {
+				t.Errorf("%T unexpected pod template annotations: %v", workload, template.Annotations)
+			}
+		}
+	}
+}
+
+func TestRolloutRestart(t *testing.T) {
+	c := fake.NewFakeClient(testStatefulSet())
+	key := types.NamespacedName{Name: "sts", Namespace: "test"}
+
+	sts := &appsv1.StatefulSet{}
+	if err := c.Get(context.TODO(), key, sts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := RolloutRestart(context.TODO(), c, sts, "manual"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := c.Get(context.TODO(), key, sts); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sts.Spec.Template.Annotations[RestartReasonAnnotation] != "manual" ||
+		sts.Spec.Template.Annotations[RestartedAtAnnotation] == "" {
+		t.Errorf("Unexpected pod template annotations: %v", sts.Spec.Template.Annotations)
+	}
+
+	if err := RolloutRestart(context.TODO(), c, &corev1.Pod{}, "manual"); err == nil {
+		t.Errorf("Didn't get expected error for unsupported workload type")
+	}
+}
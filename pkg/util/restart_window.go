@@ -0,0 +1,217 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+Defer the restart for a cert change to a maintenance window:
+
+util.PendingRestart(ctx, c, sts, certHash)
+...
+restarted, pending, err := util.ApplyPendingRestarts(ctx, c, namespace, labels, window, time.Now())
+
+PendingRestart only records the new hash on the workload, ApplyPendingRestarts
+sets it on the pod template, like RollRestartForCertChange does, once
+the current time is within the window. pending can be reported in the status.
+*/
+
+const (
+	// PendingCertHashAnnotation - workload annotation carrying the cert hash
+	// waiting to be rolled out in the next restart window
+	PendingCertHashAnnotation = AnnotationPrefix + "/pending-certs-hash"
+)
+
+// TimeWindow - daily window of hours [StartHour, EndHour) in local time,
+// wrapping over midnight if EndHour < StartHour. Equal hours mean all day.
+type TimeWindow struct {
+	StartHour int
+	EndHour   int
+}
+
+// ParseTimeWindow - parse a window in the form "22-4"
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 {
+		return TimeWindow{}, fmt.Errorf("Invalid time window %q, expected <start hour>-<end hour>", s)
+	}
+
+	hours := []int{}
+	for _, p := range parts {
+		hour, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || hour < 0 || hour > 24 {
+			return TimeWindow{}, fmt.Errorf("Invalid hour %q in time window %q", p, s)
+		}
+		hours = append(hours, hour)
+	}
+
+	return TimeWindow{StartHour: hours[0], EndHour: hours[1]}, nil
+}
+
+// Contains - returns true if t is within the window
+func (w TimeWindow) Contains(t time.Time) bool {
+	hour := t.Hour()
+	start := w.StartHour % 24
+	end := w.EndHour % 24
+	switch {
+	case start == end:
+		return true
+	case start < end:
+		return hour >= start && hour < end
+	default:
+		return hour >= start || hour < end
+	}
+}
+
+// PendingRestart - record the new cert hash on the workload without touching
+// its pod template. Returns true if a restart is pending, i.e. the hash
+// differs from the one the pods run with.
+func PendingRestart(ctx context.Context, c client.Client, workload runtime.Object, newCertHash string) (bool, error) {
+	template, err := getPodTemplate(workload)
+	if err != nil {
+		return false, err
+	}
+	accessor, err := meta.Accessor(workload)
+	if err != nil {
+		return false, err
+	}
+
+	restartPending := template.Annotations[CertHashAnnotation] != newCertHash
+	annotations := accessor.GetAnnotations()
+	current, ok := annotations[PendingCertHashAnnotation]
+	if (restartPending && current == newCertHash) || (!restartPending && !ok) {
+		return restartPending, nil
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject())
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if restartPending {
+		annotations[PendingCertHashAnnotation] = newCertHash
+	} else {
+		delete(annotations, PendingCertHashAnnotation)
+	}
+	accessor.SetAnnotations(annotations)
+
+	if err := c.Patch(ctx, workload, patch); err != nil {
+		return false, err
+	}
+
+	return restartPending, nil
+}
+
+// ApplyPendingRestarts - roll out the pending cert hashes of the Deployments,
+// StatefulSets and DaemonSets in the namespace matching the labels if now is
+// within the window. now is passed in, usually time.Now(), so the window
+// check can be tested with fixed times. Returns the number of restarted
+// workloads and of those still waiting for the window.
+func ApplyPendingRestarts(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	labels map[string]string,
+	window TimeWindow,
+	now time.Time,
+) (int, int, error) {
+	workloads, err := listWorkloads(ctx, c, namespace, labels)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	inWindow := window.Contains(now)
+	restarted := 0
+	pending := 0
+	for _, workload := range workloads {
+		accessor, err := meta.Accessor(workload)
+		if err != nil {
+			return restarted, pending, err
+		}
+		hash, ok := accessor.GetAnnotations()[PendingCertHashAnnotation]
+		if !ok {
+			continue
+		}
+		if !inWindow {
+			pending++
+			continue
+		}
+
+		template, err := getPodTemplate(workload)
+		if err != nil {
+			return restarted, pending, err
+		}
+		patch := client.MergeFrom(workload.DeepCopyObject())
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[CertHashAnnotation] = hash
+		template.Annotations[RestartedAtAnnotation] = now.Format(time.RFC3339)
+		template.Annotations[RestartReasonAnnotation] = "certs changed"
+		annotations := accessor.GetAnnotations()
+		delete(annotations, PendingCertHashAnnotation)
+		accessor.SetAnnotations(annotations)
+
+		if err := c.Patch(ctx, workload, patch); err != nil {
+			return restarted, pending, err
+		}
+		restarted++
+	}
+
+	return restarted, pending, nil
+}
+
+func listWorkloads(ctx context.Context, c client.Client, namespace string, labels map[string]string) ([]runtime.Object, error) {
+	opts := []client.ListOption{client.InNamespace(namespace), client.MatchingLabels(labels)}
+	workloads := []runtime.Object{}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, opts...); err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		workloads = append(workloads, &deployments.Items[i])
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, statefulSets, opts...); err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		workloads = append(workloads, &statefulSets.Items[i])
+	}
+
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := c.List(ctx, daemonSets, opts...); err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		workloads = append(workloads, &daemonSets.Items[i])
+	}
+
+	return workloads, nil
+}
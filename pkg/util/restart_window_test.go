@@ -0,0 +1,101 @@
+package util
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestTimeWindowContains(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2020, 1, 1, hour, 30, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		window   string
+		hour     int
+		expected bool
+	}{
+		{"2-4", 1, false},
+		{"2-4", 2, true},
+		{"2-4", 4, false},
+		{"22-4", 23, true},
+		{"22-4", 3, true},
+		{"22-4", 12, false},
+		{"0-24", 12, true},
+	}
+
+	for _, test := range tests {
+		window, err := ParseTimeWindow(test.window)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if window.Contains(at(test.hour)) != test.expected {
+			t.Errorf("Window %s hour %d; Expected: %v", test.window, test.hour, test.expected)
+		}
+	}
+
+	for _, invalid := range []string{"2", "a-4", "2-25"} {
+		if _, err := ParseTimeWindow(invalid); err == nil {
+			t.Errorf("Didn't get expected error for window %q", invalid)
+		}
+	}
+}
+
+func TestApplyPendingRestarts(t *testing.T) {
+	sts := testStatefulSet()
+	sts.Labels = map[string]string{"app": "api"}
+	sts.Spec.Template.Annotations = map[string]string{CertHashAnnotation: "hash1"}
+	c := fake.NewFakeClient(sts)
+	key := types.NamespacedName{Name: "sts", Namespace: "test"}
+
+	get := func() *appsv1.StatefulSet {
+		sts := &appsv1.StatefulSet{}
+		if err := c.Get(context.TODO(), key, sts); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return sts
+	}
+
+	pending, err := PendingRestart(context.TODO(), c, get(), "hash1")
+	if err != nil || pending {
+		t.Errorf("Expected no pending restart for unchanged hash; Got: %v, %v", pending, err)
+	}
+	pending, err = PendingRestart(context.TODO(), c, get(), "hash2")
+	if err != nil || !pending {
+		t.Errorf("Expected pending restart for changed hash; Got: %v, %v", pending, err)
+	}
+	if get().Annotations[PendingCertHashAnnotation] != "hash2" {
+		t.Errorf("Expected pending hash hash2; Got: %v", get().Annotations)
+	}
+
+	window := TimeWindow{StartHour: 22, EndHour: 4}
+	noon := time.Date(2020, 1, 1, 12, 0, 0, 0, time.Local)
+	restarted, waiting, err := ApplyPendingRestarts(context.TODO(), c, "test", sts.Labels, window, noon)
+	if err != nil || restarted != 0 || waiting != 1 {
+		t.Errorf("Expected 1 pending restart outside the window; Got: %d, %d, %v", restarted, waiting, err)
+	}
+	if get().Spec.Template.Annotations[CertHashAnnotation] != "hash1" {
+		t.Errorf("Didn't expect pod template to change outside the window")
+	}
+
+	night := time.Date(2020, 1, 1, 23, 30, 0, 0, time.Local)
+	restarted, waiting, err = ApplyPendingRestarts(context.TODO(), c, "test", sts.Labels, window, night)
+	if err != nil || restarted != 1 || waiting != 0 {
+		t.Errorf("Expected 1 restart inside the window; Got: %d, %d, %v", restarted, waiting, err)
+	}
+	updated := get()
+	if updated.Spec.Template.Annotations[CertHashAnnotation] != "hash2" {
+		t.Errorf("Expected pod template hash hash2; Got: %v", updated.Spec.Template.Annotations)
+	}
+	if _, ok := updated.Annotations[PendingCertHashAnnotation]; ok {
+		t.Errorf("Expected pending hash to be removed; Got: %v", updated.Annotations)
+	}
+	if updated.Spec.Template.Annotations[RestartedAtAnnotation] != night.Format(time.RFC3339) {
+		t.Errorf("Expected: %s; Got: %s", night.Format(time.RFC3339), updated.Spec.Template.Annotations[RestartedAtAnnotation])
+	}
+}
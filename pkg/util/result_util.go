@@ -0,0 +1,145 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+Aggregate the results of multiple ensure steps of a reconcile:
+
+rc := util.ResultCollector{}
+rc.Add("configmaps", ensureConfigMaps())
+rc.Add("job", ensureJob())
+if rc.RequiresRequeue() {
+	// rc.Conditions() summarizes which step requested the requeue
+	return rc.ShortestRequeue(), rc.FirstError()
+}
+*/
+
+const (
+	// StepReasonCompleted - the step completed
+	StepReasonCompleted = "Completed"
+	// StepReasonRequeue - the step requested a requeue
+	StepReasonRequeue = "Requeue"
+	// StepReasonError - the step failed
+	StepReasonError = "Error"
+)
+
+// StepCondition - summary of the result of a reconcile step
+type StepCondition struct {
+	// Type - name of the step
+	Type string
+	// Status - True if the step completed
+	Status  corev1.ConditionStatus
+	Reason  string
+	Message string
+}
+
+// stepResult - result of a single reconcile step
+type stepResult struct {
+	step   string
+	result reconcile.Result
+	err    error
+}
+
+// ResultCollector - collects the results of multiple reconcile steps
+type ResultCollector struct {
+	results []stepResult
+}
+
+// Add - record the result of a reconcile step. The step name identifies the
+// step in RequeueSteps and Conditions.
+func (rc *ResultCollector) Add(step string, result reconcile.Result, err error) {
+	rc.results = append(rc.results, stepResult{step: step, result: result, err: err})
+}
+
+// RequiresRequeue - returns true if any step returned an error or requested a requeue
+func (rc *ResultCollector) RequiresRequeue() bool {
+	return len(rc.RequeueSteps()) > 0
+}
+
+// ShortestRequeue - returns the result with the shortest RequeueAfter of all
+// steps requesting a requeue. A plain Requeue without delay wins.
+func (rc *ResultCollector) ShortestRequeue() reconcile.Result {
+	shortest := reconcile.Result{}
+	for _, r := range rc.results {
+		switch {
+		case r.result.Requeue && r.result.RequeueAfter == 0:
+			return r.result
+		case r.result.RequeueAfter > 0:
+			if shortest.RequeueAfter == 0 || r.result.RequeueAfter < shortest.RequeueAfter {
+				shortest = r.result
+			}
+		}
+	}
+	return shortest
+}
+
+// FirstError - returns the error of the first failed step
+func (rc *ResultCollector) FirstError() error {
+	for _, r := range rc.results {
+		if r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
+
+// RequeueSteps - returns the names of the steps which returned an error or
+// requested a requeue, in the order they got added
+func (rc *ResultCollector) RequeueSteps() []string {
+	steps := []string{}
+	for _, r := range rc.results {
+		if r.err != nil || r.result.Requeue || r.result.RequeueAfter > 0 {
+			steps = append(steps, r.step)
+		}
+	}
+	return steps
+}
+
+// Conditions - returns a condition per step, in the order they got added,
+// summarizing if the step completed, requested a requeue or failed
+func (rc *ResultCollector) Conditions() []StepCondition {
+	conditions := []StepCondition{}
+	for _, r := range rc.results {
+		cond := StepCondition{
+			Type:   r.step,
+			Status: corev1.ConditionFalse,
+		}
+		switch {
+		case r.err != nil:
+			cond.Reason = StepReasonError
+			cond.Message = r.err.Error()
+		case r.result.RequeueAfter > 0:
+			cond.Reason = StepReasonRequeue
+			cond.Message = fmt.Sprintf("Requeue after %s", r.result.RequeueAfter)
+		case r.result.Requeue:
+			cond.Reason = StepReasonRequeue
+			cond.Message = "Requeue"
+		default:
+			cond.Status = corev1.ConditionTrue
+			cond.Reason = StepReasonCompleted
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions
+}
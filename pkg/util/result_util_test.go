@@ -0,0 +1,115 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestResultCollector(t *testing.T) {
+	rc := ResultCollector{}
+	rc.Add("secret", reconcile.Result{}, nil)
+	if rc.RequiresRequeue() {
+		t.Errorf("Didn't expect requeue")
+	}
+
+	rc.Add("configmap", reconcile.Result{RequeueAfter: 10 * time.Second}, nil)
+	rc.Add("job", reconcile.Result{RequeueAfter: 5 * time.Second}, nil)
+	rc.Add("statefulset", reconcile.Result{}, nil)
+
+	if !rc.RequiresRequeue() {
+		t.Errorf("Expected requeue")
+	}
+	if res := rc.ShortestRequeue(); res.RequeueAfter != 5*time.Second {
+		t.Errorf("Expected requeue after 5s; Got: %v", res)
+	}
+	if !reflect.DeepEqual(rc.RequeueSteps(), []string{"configmap", "job"}) {
+		t.Errorf("Unexpected requeue steps: %v", rc.RequeueSteps())
+	}
+	if rc.FirstError() != nil {
+		t.Errorf("Didn't expect an error; Got: %v", rc.FirstError())
+	}
+
+	failed := errors.New("failed")
+	rc.Add("deployment", reconcile.Result{}, failed)
+	rc.Add("service", reconcile.Result{}, errors.New("other"))
+	if rc.FirstError() != failed {
+		t.Errorf("Expected first error; Got: %v", rc.FirstError())
+	}
+
+	rc.Add("route", reconcile.Result{Requeue: true}, nil)
+	if res := rc.ShortestRequeue(); !res.Requeue || res.RequeueAfter != 0 {
+		t.Errorf("Expected immediate requeue; Got: %v", res)
+	}
+}
+
+// TestResultCollectorEnsureFlow - tls, secret and statefulset steps of a
+// reconcile where the middle step requeues as its source secret is missing
+func TestResultCollectorEnsureFlow(t *testing.T) {
+	ctx := context.TODO()
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "test", UID: "uid"}}
+	sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "test"}}
+	c := fake.NewFakeClient(owner, sts)
+
+	rc := ResultCollector{}
+
+	_, err := GetTLSConfig(nil, nil)
+	rc.Add("tls", reconcile.Result{}, err)
+
+	hash, err := EnsureFilteredSecretCopy(ctx, c, owner, scheme.Scheme,
+		types.NamespacedName{Name: "db-password", Namespace: "test"},
+		types.NamespacedName{Name: "db-env", Namespace: "test"},
+		[]string{"password"}, nil)
+	if k8s_errors.IsNotFound(err) {
+		rc.Add("secret", reconcile.Result{RequeueAfter: 10 * time.Second}, nil)
+	} else {
+		rc.Add("secret", reconcile.Result{}, err)
+	}
+
+	_, err = RestartOnHashChange(ctx, c, sts, "secret", hash)
+	rc.Add("statefulset", reconcile.Result{}, err)
+
+	if !rc.RequiresRequeue() {
+		t.Fatalf("Expected requeue")
+	}
+	if res := rc.ShortestRequeue(); res.RequeueAfter != 10*time.Second {
+		t.Errorf("Expected: requeue after 10s; Got: %v", res)
+	}
+	if rc.FirstError() != nil {
+		t.Errorf("Didn't expect an error; Got: %v", rc.FirstError())
+	}
+
+	expected := []StepCondition{
+		{Type: "tls", Status: corev1.ConditionTrue, Reason: StepReasonCompleted},
+		{Type: "secret", Status: corev1.ConditionFalse, Reason: StepReasonRequeue, Message: "Requeue after 10s"},
+		{Type: "statefulset", Status: corev1.ConditionTrue, Reason: StepReasonCompleted},
+	}
+	if !reflect.DeepEqual(rc.Conditions(), expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, rc.Conditions())
+	}
+}
+
+func TestResultCollectorConditionsError(t *testing.T) {
+	rc := ResultCollector{}
+	rc.Add("job", reconcile.Result{Requeue: true}, nil)
+	rc.Add("route", reconcile.Result{}, errors.New("failed"))
+
+	expected := []StepCondition{
+		{Type: "job", Status: corev1.ConditionFalse, Reason: StepReasonRequeue, Message: "Requeue"},
+		{Type: "route", Status: corev1.ConditionFalse, Reason: StepReasonError, Message: "failed"},
+	}
+	if !reflect.DeepEqual(rc.Conditions(), expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, rc.Conditions())
+	}
+}
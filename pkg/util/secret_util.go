@@ -0,0 +1,161 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// SourceHashAnnotation - annotation carrying the hash of the data copied from the source
+	SourceHashAnnotation = AnnotationPrefix + "/source-hash"
+)
+
+// GetSecret - get the secret from the explicit namespace, which can differ
+// from the namespace of the CR, and return it with the hash of its data
+func GetSecret(ctx context.Context, c client.Client, namespace string, name string) (*corev1.Secret, string, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash, err := ComputeInputHash(SecretHashSource(secret))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return secret, hash, nil
+}
+
+// EnsureFilteredSecretCopy - create or update the dst secret with the data of
+// the src secret, limited to allowKeys (all keys if empty) and without
+// denyKeys. As envFrom can not exclude keys, the filtered copy can be used
+// to expose a subset of a secret as env vars. The copy is always of type
+// Opaque, as the filtered data may not satisfy the type of the source, e.g.
+// kubernetes.io/tls without tls.key, and is controlled by the owner so it
+// gets garbage collected with it. Changes to the data of the copy get
+// reverted. Returns the hash of the copied data.
+func EnsureFilteredSecretCopy(
+	ctx context.Context,
+	c client.Client,
+	owner metav1.Object,
+	scheme *runtime.Scheme,
+	src types.NamespacedName,
+	dst types.NamespacedName,
+	allowKeys []string,
+	denyKeys []string,
+) (string, error) {
+	srcSecret := &corev1.Secret{}
+	if err := c.Get(ctx, src, srcSecret); err != nil {
+		return "", err
+	}
+
+	data := filterSecretData(srcSecret.Data, allowKeys, denyKeys)
+	hash, err := ObjectHash(data)
+	if err != nil {
+		return "", err
+	}
+
+	dstSecret := &corev1.Secret{}
+	err = c.Get(ctx, dst, dstSecret)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return "", err
+	}
+	exists := err == nil
+	if exists && dstSecret.Type != corev1.SecretTypeOpaque {
+		// the type of a secret is immutable, recreate it
+		if err := c.Delete(ctx, dstSecret); err != nil && !k8s_errors.IsNotFound(err) {
+			return "", err
+		}
+		exists = false
+	}
+
+	if !exists {
+		dstSecret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        dst.Name,
+				Namespace:   dst.Namespace,
+				Annotations: map[string]string{SourceHashAnnotation: hash},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		if err := controllerutil.SetControllerReference(owner, dstSecret, scheme); err != nil {
+			return "", err
+		}
+		return hash, c.Create(ctx, dstSecret)
+	}
+
+	ref, ok := GetControllerOwnerRef(dstSecret)
+	if dstSecret.Annotations[SourceHashAnnotation] == hash &&
+		equalSecretData(dstSecret.Data, data) &&
+		ok && ref.UID == owner.GetUID() {
+		return hash, nil
+	}
+
+	if err := controllerutil.SetControllerReference(owner, dstSecret, scheme); err != nil {
+		return "", err
+	}
+	if dstSecret.Annotations == nil {
+		dstSecret.Annotations = map[string]string{}
+	}
+	dstSecret.Annotations[SourceHashAnnotation] = hash
+	dstSecret.Data = data
+
+	return hash, c.Update(ctx, dstSecret)
+}
+
+func equalSecretData(a map[string][]byte, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || !bytes.Equal(v, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func filterSecretData(data map[string][]byte, allowKeys []string, denyKeys []string) map[string][]byte {
+	allowed := map[string]bool{}
+	for _, k := range allowKeys {
+		allowed[k] = true
+	}
+	denied := map[string]bool{}
+	for _, k := range denyKeys {
+		denied[k] = true
+	}
+
+	filtered := map[string][]byte{}
+	for k, v := range data {
+		if (len(allowed) == 0 || allowed[k]) && !denied[k] {
+			filtered[k] = v
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureFilteredSecretCopy(t *testing.T) {
+	src := types.NamespacedName{Name: "src", Namespace: "test"}
+	dst := types.NamespacedName{Name: "dst", Namespace: "test"}
+	owner := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "test", UID: "owner-uid"}}
+	c := fake.NewFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: src.Name, Namespace: src.Namespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			"user":     []byte("admin"),
+			"password": []byte("secret"),
+			"tls.crt":  []byte("cert"),
+			"tls.key":  []byte("key"),
+		},
+	})
+
+	getDst := func() *corev1.Secret {
+		secret := &corev1.Secret{}
+		if err := c.Get(context.TODO(), dst, secret); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return secret
+	}
+
+	hash1, err := EnsureFilteredSecretCopy(context.TODO(), c, owner, scheme.Scheme, src, dst, nil, []string{"tls.crt", "tls.key"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string][]byte{"user": []byte("admin"), "password": []byte("secret")}
+	if !reflect.DeepEqual(getDst().Data, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, getDst().Data)
+	}
+	if getDst().Type != corev1.SecretTypeOpaque {
+		t.Errorf("Expected type %s; Got: %s", corev1.SecretTypeOpaque, getDst().Type)
+	}
+	if ref, ok := GetControllerOwnerRef(getDst()); !ok || ref.UID != owner.UID {
+		t.Errorf("Expected controller reference to owner; Got: %v", getDst().OwnerReferences)
+	}
+
+	hash2, err := EnsureFilteredSecretCopy(context.TODO(), c, owner, scheme.Scheme, src, dst, []string{"user"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = map[string][]byte{"user": []byte("admin")}
+	if !reflect.DeepEqual(getDst().Data, expected) || hash1 == hash2 {
+		t.Errorf("Expected: %v with a new hash; Got: %v", expected, getDst().Data)
+	}
+
+	// source update propagates
+	srcSecret := &corev1.Secret{}
+	if err := c.Get(context.TODO(), src, srcSecret); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	srcSecret.Data["user"] = []byte("changed")
+	if err := c.Update(context.TODO(), srcSecret); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hash3, err := EnsureFilteredSecretCopy(context.TODO(), c, owner, scheme.Scheme, src, dst, []string{"user"}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected = map[string][]byte{"user": []byte("changed")}
+	if !reflect.DeepEqual(getDst().Data, expected) || getDst().Annotations[SourceHashAnnotation] != hash3 {
+		t.Errorf("Expected: %v with hash %s; Got: %v", expected, hash3, getDst())
+	}
+
+	// hand edits of the copy get reverted
+	edited := getDst()
+	edited.Data["user"] = []byte("edited")
+	if err := c.Update(context.TODO(), edited); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := EnsureFilteredSecretCopy(context.TODO(), c, owner, scheme.Scheme, src, dst, []string{"user"}, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(getDst().Data, expected) {
+		t.Errorf("Expected edit to be reverted to: %v; Got: %v", expected, getDst().Data)
+	}
+}
+
+func TestEnsureEnvFromSecret(t *testing.T) {
+	container := corev1.Container{}
+	EnsureEnvFromSecret(&container, "dst", "OLD_")
+	EnsureEnvFromSecret(&container, "dst", "DB_")
+
+	if len(container.EnvFrom) != 1 || container.EnvFrom[0].Prefix != "DB_" || container.EnvFrom[0].SecretRef.Name != "dst" {
+		t.Errorf("Unexpected envFrom: %v", container.EnvFrom)
+	}
+}
+
+func TestGetSecretFromOtherNamespace(t *testing.T) {
+	c := fake.NewFakeClient(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "shared", Namespace: "other"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	})
+
+	secret, hash, err := GetSecret(context.TODO(), c, "other", "shared")
+	if err != nil || string(secret.Data["password"]) != "secret" || hash == "" {
+		t.Errorf("Expected secret from namespace other; Got: %v, %s, %v", secret, hash, err)
+	}
+	if _, _, err := GetSecret(context.TODO(), c, "test", "shared"); err == nil {
+		t.Errorf("Didn't expect secret in namespace test")
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UpdateStatusWithRetry - apply mutate to the object and update its status.
+// On conflict the object gets fetched again and the mutation re-applied, up
+// to the steps of retry.DefaultRetry.
+func UpdateStatusWithRetry(ctx context.Context, c client.Client, obj runtime.Object, mutate func() error) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	key := types.NamespacedName{Name: accessor.GetName(), Namespace: accessor.GetNamespace()}
+
+	first := true
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if !first {
+			if err := c.Get(ctx, key, obj); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		if err := mutate(); err != nil {
+			return err
+		}
+		return c.Status().Update(ctx, obj)
+	})
+}
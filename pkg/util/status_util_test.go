@@ -0,0 +1,74 @@
+package util
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// conflictingClient - client failing the first status updates with a conflict
+type conflictingClient struct {
+	client.Client
+	conflicts int
+}
+
+func (c *conflictingClient) Status() client.StatusWriter {
+	return &conflictingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type conflictingStatusWriter struct {
+	client.StatusWriter
+	client *conflictingClient
+}
+
+func (w *conflictingStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if w.client.conflicts > 0 {
+		w.client.conflicts--
+		return k8s_errors.NewConflict(schema.GroupResource{Resource: "pods"}, "pod", nil)
+	}
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+func TestUpdateStatusWithRetry(t *testing.T) {
+	c := &conflictingClient{
+		Client: fake.NewFakeClient(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "test"},
+		}),
+		conflicts: 1,
+	}
+	key := types.NamespacedName{Name: "pod", Namespace: "test"}
+
+	pod := &corev1.Pod{}
+	if err := c.Get(context.TODO(), key, pod); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	calls := 0
+	err := UpdateStatusWithRetry(context.TODO(), c, pod, func() error {
+		calls++
+		pod.Status.Message = "updated"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected mutation to be applied twice; Got: %d", calls)
+	}
+
+	pod = &corev1.Pod{}
+	if err := c.Get(context.TODO(), key, pod); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if pod.Status.Message != "updated" {
+		t.Errorf("Expected status to be updated; Got: %v", pod.Status)
+	}
+}
@@ -0,0 +1,50 @@
+package util
+
+import (
+	"crypto/tls"
+	"reflect"
+	"testing"
+)
+
+func TestGetTLSConfig(t *testing.T) {
+	v12 := "1.2"
+	v13 := "1.3"
+	invalid := "2.0"
+
+	tests := []struct {
+		minVersion *string
+		ciphers    []string
+		expected   *tls.Config
+		err        bool
+	}{
+		{nil, nil, &tls.Config{}, false},
+		{&v13, nil, &tls.Config{MinVersion: tls.VersionTLS13}, false},
+		{
+			&v12,
+			[]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"},
+			&tls.Config{
+				MinVersion: tls.VersionTLS12,
+				CipherSuites: []uint16{
+					tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+					tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+				},
+			},
+			false,
+		},
+		{&invalid, nil, nil, true},
+		{nil, []string{"TLS_UNKNOWN"}, nil, true},
+		{nil, []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_AES_128_GCM_SHA256"}, nil, true},
+	}
+
+	for _, test := range tests {
+		config, err := GetTLSConfig(test.minVersion, test.ciphers)
+		switch {
+		case test.err && err == nil:
+			t.Errorf("Didn't get expected error for %v %v", test.minVersion, test.ciphers)
+		case !test.err && err != nil:
+			t.Errorf("Unexpected error: %v", err)
+		case !test.err && !reflect.DeepEqual(config, test.expected):
+			t.Errorf("Expected: %v; Got: %v", test.expected, config)
+		}
+	}
+}
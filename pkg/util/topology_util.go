@@ -0,0 +1,110 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// TopologyZoneKey - node label holding the zone of the node
+	TopologyZoneKey = "topology.kubernetes.io/zone"
+	// TopologyHostnameKey - node label holding the hostname of the node
+	TopologyHostnameKey = "kubernetes.io/hostname"
+)
+
+// TopologyCache - caches the topology domains of the nodes of the cluster
+// the client talks to, as they are expected to be checked on every reconcile
+type TopologyCache struct {
+	client  client.Client
+	ttl     time.Duration
+	lock    sync.Mutex
+	entries map[string]topologyCacheEntry
+}
+
+type topologyCacheEntry struct {
+	domains []string
+	expires time.Time
+}
+
+// NewTopologyCache - create a TopologyCache for the client keeping the
+// domains of a topology key for ttl
+func NewTopologyCache(c client.Client, ttl time.Duration) *TopologyCache {
+	return &TopologyCache{
+		client:  c,
+		ttl:     ttl,
+		entries: map[string]topologyCacheEntry{},
+	}
+}
+
+// GetTopologyDomains - returns the sorted distinct values of the topologyKey
+// label of all nodes
+func (tc *TopologyCache) GetTopologyDomains(ctx context.Context, topologyKey string) ([]string, error) {
+	tc.lock.Lock()
+	entry, ok := tc.entries[topologyKey]
+	tc.lock.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return append([]string{}, entry.domains...), nil
+	}
+
+	nodes := &corev1.NodeList{}
+	err := tc.client.List(ctx, nodes, client.HasLabels{topologyKey})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	domains := []string{}
+	for _, node := range nodes.Items {
+		domain := node.Labels[topologyKey]
+		if domain != "" && !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+
+	tc.lock.Lock()
+	tc.entries[topologyKey] = topologyCacheEntry{
+		domains: domains,
+		expires: time.Now().Add(tc.ttl),
+	}
+	tc.lock.Unlock()
+
+	return append([]string{}, domains...), nil
+}
+
+// PickTopologyKey - returns the zone topology key if there are at least as
+// many zones as replicas, so each replica can be placed in its own zone,
+// otherwise the hostname topology key to spread the replicas over nodes
+func (tc *TopologyCache) PickTopologyKey(ctx context.Context, replicas int32) (string, error) {
+	zones, err := tc.GetTopologyDomains(ctx, TopologyZoneKey)
+	if err != nil {
+		return "", err
+	}
+
+	if replicas > 1 && int32(len(zones)) >= replicas {
+		return TopologyZoneKey, nil
+	}
+	return TopologyHostnameKey, nil
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func nodesInZones(zones ...string) []runtime.Object {
+	nodes := []runtime.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+	}
+	for i, zone := range zones {
+		nodes = append(nodes, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("node-%d", i),
+				Labels: map[string]string{TopologyZoneKey: zone},
+			},
+		})
+	}
+	return nodes
+}
+
+func TestPickTopologyKey(t *testing.T) {
+	tests := []struct {
+		nodes    []runtime.Object
+		domains  int
+		replicas int32
+		key      string
+	}{
+		{nodesInZones("a", "a"), 1, 3, TopologyHostnameKey},
+		{nodesInZones("a", "b", "b"), 2, 3, TopologyHostnameKey},
+		{nodesInZones("a", "b", "c", "c"), 3, 3, TopologyZoneKey},
+		{nodesInZones("a", "b", "c"), 3, 1, TopologyHostnameKey},
+	}
+
+	for _, test := range tests {
+		tc := NewTopologyCache(fake.NewFakeClient(test.nodes...), time.Minute)
+
+		domains, err := tc.GetTopologyDomains(context.TODO(), TopologyZoneKey)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(domains) != test.domains {
+			t.Errorf("Expected %d domains; Got: %v", test.domains, domains)
+		}
+
+		key, err := tc.PickTopologyKey(context.TODO(), test.replicas)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if key != test.key {
+			t.Errorf("Expected: %s; Got: %s", test.key, key)
+		}
+	}
+}
+
+func TestGetTopologyDomainsCached(t *testing.T) {
+	c := fake.NewFakeClient(nodesInZones("a")...)
+	tc := NewTopologyCache(c, time.Minute)
+
+	domains, err := tc.GetTopologyDomains(context.TODO(), TopologyZoneKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// modifying the result must not change the cache
+	domains[0] = "modified"
+	_ = append(domains, "appended")
+
+	if err := c.Create(context.TODO(), nodesInZones("a", "b")[2]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	domains, err = tc.GetTopologyDomains(context.TODO(), TopologyZoneKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(domains, []string{"a"}) {
+		t.Errorf("Expected cached domains [a]; Got: %v", domains)
+	}
+
+	// another cache, e.g. of another client, does not share the entries
+	domains, err = NewTopologyCache(c, time.Minute).GetTopologyDomains(context.TODO(), TopologyZoneKey)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(domains, []string{"a", "b"}) {
+		t.Errorf("Expected domains [a b]; Got: %v", domains)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2020 Red Hat
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+Reconcile CRs when a secret they reference changes:
+
+extract := func(obj runtime.Object) []string {
+	cr := obj.(*novav1.Nova)
+	return []string{cr.Spec.TLS.SecretName, cr.Spec.TLS.CaBundleSecretName}
+}
+err := util.IndexSecretReferences(ctx, mgr.GetFieldIndexer(), &novav1.Nova{}, ".spec.tls.secrets", extract)
+...
+Watches(&source.Kind{Type: &corev1.Secret{}},
+	util.SecretReferenceHandler(mgr.GetClient(), log, &novav1.NovaList{}, ".spec.tls.secrets", extract))
+*/
+
+// secretReferenceListTimeout - timeout of the List of the CRs referencing a secret
+const secretReferenceListTimeout = 30 * time.Second
+
+// SecretNamesFunc - returns the names of the secrets a CR references
+type SecretNamesFunc func(obj runtime.Object) []string
+
+// IndexSecretReferences - index the CRs of the type of obj by the names of
+// the secrets they reference under the field name
+func IndexSecretReferences(
+	ctx context.Context,
+	indexer client.FieldIndexer,
+	obj runtime.Object,
+	field string,
+	extract SecretNamesFunc,
+) error {
+	return indexer.IndexField(ctx, obj, field, func(o runtime.Object) []string {
+		return secretNames(o, extract)
+	})
+}
+
+// SecretReferenceHandler - event handler for secrets which enqueues a
+// reconcile request for every CR in the namespace of the secret referencing
+// it. list is the list type of the CRs, field the index added with
+// IndexSecretReferences, c a reader supporting it, e.g. the manager client.
+// The listed CRs get filtered using extract again, so the handler also works
+// with readers not supporting field selectors.
+func SecretReferenceHandler(
+	c client.Reader,
+	log logr.Logger,
+	list runtime.Object,
+	field string,
+	extract SecretNamesFunc,
+) handler.EventHandler {
+	return &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(secret handler.MapObject) []reconcile.Request {
+			ctx, cancel := context.WithTimeout(context.Background(), secretReferenceListTimeout)
+			defer cancel()
+
+			crs := list.DeepCopyObject()
+			err := c.List(ctx, crs,
+				client.InNamespace(secret.Meta.GetNamespace()),
+				client.MatchingFields{field: secret.Meta.GetName()},
+			)
+			if err != nil {
+				log.Error(err, "Unable to list CRs referencing secret", "secret", secret.Meta.GetName())
+				return nil
+			}
+
+			items, err := meta.ExtractList(crs)
+			if err != nil {
+				log.Error(err, "Unable to extract CRs from list", "secret", secret.Meta.GetName())
+				return nil
+			}
+
+			requests := []reconcile.Request{}
+			for _, item := range items {
+				if !containsString(secretNames(item, extract), secret.Meta.GetName()) {
+					continue
+				}
+				accessor, err := meta.Accessor(item)
+				if err != nil {
+					continue
+				}
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: accessor.GetName(), Namespace: accessor.GetNamespace()},
+				})
+			}
+			return requests
+		}),
+	}
+}
+
+// secretNames - non empty secret names returned by extract
+func secretNames(obj runtime.Object, extract SecretNamesFunc) []string {
+	names := []string{}
+	for _, name := range extract(obj) {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
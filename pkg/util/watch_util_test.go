@@ -0,0 +1,143 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const secretsField = "spec.secrets"
+
+func podWithSecret(name string, secret string) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", ResourceVersion: "1"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name:         "certs",
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secret}},
+			}},
+		},
+	}
+}
+
+func podSecretNames(obj runtime.Object) []string {
+	names := []string{}
+	for _, v := range obj.(*corev1.Pod).Spec.Volumes {
+		if v.Secret != nil {
+			names = append(names, v.Secret.SecretName)
+		}
+	}
+	return names
+}
+
+// secretUpdateRequests - run the handler for an update of the secret cert and return the enqueued requests
+func secretUpdateRequests(c client.Reader) []interface{} {
+	h := SecretReferenceHandler(c, &capturingLogger{}, &corev1.PodList{}, secretsField, podSecretNames)
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "cert", Namespace: "test"}}
+	h.Update(event.UpdateEvent{MetaOld: secret, ObjectOld: secret, MetaNew: secret, ObjectNew: secret}, q)
+
+	requests := []interface{}{}
+	for q.Len() > 0 {
+		item, _ := q.Get()
+		requests = append(requests, item)
+		q.Done(item)
+	}
+	return requests
+}
+
+func TestSecretReferenceHandler(t *testing.T) {
+	c := fake.NewFakeClient(
+		podWithSecret("referencing", "cert"),
+		podWithSecret("other", "other-cert"),
+	)
+
+	requests := secretUpdateRequests(c)
+	expected := []interface{}{
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "referencing", Namespace: "test"}},
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, requests)
+	}
+}
+
+// TestSecretReferenceHandlerWithCache - use the informer cache of a manager,
+// fed by a minimal API server, so the field index gets used
+func TestSecretReferenceHandlerWithCache(t *testing.T) {
+	pods := &corev1.PodList{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"},
+		ListMeta: metav1.ListMeta{ResourceVersion: "1"},
+		Items: []corev1.Pod{
+			*podWithSecret("referencing", "cert"),
+			*podWithSecret("other", "other-cert"),
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("watch") == "true" {
+			// no changes, keep the watch open until the client goes away
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		_ = json.NewEncoder(w).Encode(pods)
+	}))
+	defer server.Close()
+	defer server.CloseClientConnections()
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+	informers, err := cache.New(&rest.Config{Host: server.URL}, cache.Options{Scheme: scheme.Scheme, Mapper: mapper})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := IndexSecretReferences(context.TODO(), informers, &corev1.Pod{}, secretsField, podSecretNames); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		_ = informers.Start(stop)
+	}()
+	if !informers.WaitForCacheSync(stop) {
+		t.Fatalf("Cache did not sync")
+	}
+
+	indexed := &corev1.PodList{}
+	if err := informers.List(context.TODO(), indexed, client.MatchingFields{secretsField: "cert"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(indexed.Items) != 1 || indexed.Items[0].Name != "referencing" {
+		t.Errorf("Expected index to return pod referencing; Got: %v", indexed.Items)
+	}
+
+	requests := secretUpdateRequests(informers)
+	expected := []interface{}{
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "referencing", Namespace: "test"}},
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected: %v; Got: %v", expected, requests)
+	}
+}